@@ -0,0 +1,96 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// benchCalls reads or writes one of each fixed width and varint primitive.
+var benchCalls = []struct {
+	name   string
+	decode func(d *Decoder)
+	encode func(e *Encoder)
+}{
+	{"Uint8", func(d *Decoder) { d.Uint8() }, func(e *Encoder) { e.Uint8(1) }},
+	{"Uint16", func(d *Decoder) { d.Uint16() }, func(e *Encoder) { e.Uint16(0x1111) }},
+	{"Uint32", func(d *Decoder) { d.Uint32() }, func(e *Encoder) { e.Uint32(0x11111111) }},
+	{"Uint64", func(d *Decoder) { d.Uint64() }, func(e *Encoder) { e.Uint64(0x1111111111111111) }},
+	{"Int8", func(d *Decoder) { d.Int8() }, func(e *Encoder) { e.Int8(-1) }},
+	{"Int16", func(d *Decoder) { d.Int16() }, func(e *Encoder) { e.Int16(-1) }},
+	{"Int32", func(d *Decoder) { d.Int32() }, func(e *Encoder) { e.Int32(-1) }},
+	{"Int64", func(d *Decoder) { d.Int64() }, func(e *Encoder) { e.Int64(-1) }},
+	{"Float32", func(d *Decoder) { d.Float32() }, func(e *Encoder) { e.Float32(1.5) }},
+	{"Float64", func(d *Decoder) { d.Float64() }, func(e *Encoder) { e.Float64(1.5) }},
+	{"Bool", func(d *Decoder) { d.Bool() }, func(e *Encoder) { e.Bool(true) }},
+	{"Byte", func(d *Decoder) { d.Byte() }, func(e *Encoder) { e.Byte(1) }},
+	{"Uvarint", func(d *Decoder) { d.Uvarint() }, func(e *Encoder) { e.Uvarint(300) }},
+	{"Varint", func(d *Decoder) { d.Varint() }, func(e *Encoder) { e.Varint(-300) }},
+}
+
+// benchData decodes to large non-zero values, which unlike small ones would allocate if boxed in an interface.
+var benchData = append([]byte{0x85, 0x82}, bytes.Repeat([]byte{0x11}, 62)...)
+
+func TestPrimitiveAllocs(t *testing.T) {
+	data := benchData
+	r := bytes.NewReader(data)
+	dec := NewDecoder(r, binary.LittleEndian)
+	enc := NewEncoder(io.Discard, binary.LittleEndian)
+	for _, bc := range benchCalls {
+		allocs := testing.AllocsPerRun(100, func() {
+			r.Reset(data)
+			bc.decode(dec)
+		})
+		if allocs != 0 {
+			t.Errorf("Decoder.%s: %v allocs per call, want 0", bc.name, allocs)
+		}
+		allocs = testing.AllocsPerRun(100, func() {
+			bc.encode(enc)
+		})
+		if allocs != 0 {
+			t.Errorf("Encoder.%s: %v allocs per call, want 0", bc.name, allocs)
+		}
+	}
+	if dec.Error() != nil || enc.Error() != nil {
+		t.Fatalf("unexpected errors: %v, %v", dec.Error(), enc.Error())
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	data := benchData
+	for _, bc := range benchCalls {
+		b.Run(bc.name, func(b *testing.B) {
+			r := bytes.NewReader(data)
+			dec := NewDecoder(r, binary.LittleEndian)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.Reset(data)
+				bc.decode(dec)
+			}
+		})
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	for _, bc := range benchCalls {
+		b.Run(bc.name, func(b *testing.B) {
+			enc := NewEncoder(io.Discard, binary.LittleEndian)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bc.encode(enc)
+			}
+		})
+	}
+}
+
+func BenchmarkBinaryRead(b *testing.B) {
+	data := make([]byte, 64)
+	r := bytes.NewReader(data)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		var v uint32
+		binary.Read(r, binary.LittleEndian, &v)
+	}
+}
@@ -0,0 +1,171 @@
+package encdec
+
+import (
+	"fmt"
+	"io"
+)
+
+// BitOrder is the order bits are packed into each byte by Bits and Bit.
+type BitOrder int
+
+const (
+	// MSBFirst packs bits starting at the most significant bit of each byte, with the most significant bit of a value first.
+	MSBFirst BitOrder = iota
+	// LSBFirst packs bits starting at the least significant bit of each byte, with the least significant bit of a value first.
+	LSBFirst
+)
+
+// String returns the name of the bit order.
+func (o BitOrder) String() string {
+	switch o {
+	case MSBFirst:
+		return "MSBFirst"
+	case LSBFirst:
+		return "LSBFirst"
+	}
+	return fmt.Sprintf("BitOrder(%d)", int(o))
+}
+
+// SetBitOrder sets the bit order used by Bits and Bit.
+func (d *Decoder) SetBitOrder(order BitOrder) {
+	d.bitOrder = order
+}
+
+// BitPos returns the current position in bits, including bits read from a partially consumed byte.
+func (d *Decoder) BitPos() int64 {
+	pos := d.Pos()
+	if pos < 0 {
+		return -1
+	}
+	return pos*8 - int64(d.bitLeft)
+}
+
+// Bits returns the next n bits (up to 64) as an unsigned value.
+// Any byte aligned read that follows discards the rest of a partially consumed byte.
+func (d *Decoder) Bits(n int) uint64 {
+	c := d.begin("Bits")
+	v := d.bits(c, n)
+	traceRead(d, c, v)
+	return v
+}
+
+// Bit returns the next bit.
+func (d *Decoder) Bit() bool {
+	c := d.begin("Bit")
+	v := d.bits(c, 1) != 0
+	traceRead(d, c, v)
+	return v
+}
+
+// AlignByte discards the unread bits of a partially consumed byte.
+func (d *Decoder) AlignByte() {
+	d.bitLeft = 0
+}
+
+// bits reads n bits, loading bytes from the stream as needed.
+func (d *Decoder) bits(c call, n int) uint64 {
+	if n < 0 || n > 64 {
+		d.fail(c, d.Pos(), 0, fmt.Errorf("bit count %d out of range 0-64", n))
+		return 0
+	}
+	var v uint64
+	for i := 0; i < n; i++ {
+		if d.bitLeft == 0 {
+			buf := d.scratch[:1]
+			pos := d.Pos()
+			_, err := io.ReadFull(d.reader(), buf)
+			if err != nil {
+				d.fail(c, pos, 1, err)
+				return v
+			}
+			d.bitCur = buf[0]
+			d.bitLeft = 8
+		}
+		if d.bitOrder == LSBFirst {
+			bit := uint64(d.bitCur>>(8-d.bitLeft)) & 1
+			v |= bit << i
+		} else {
+			bit := uint64(d.bitCur>>(d.bitLeft-1)) & 1
+			v = v<<1 | bit
+		}
+		d.bitLeft--
+	}
+	return v
+}
+
+// SetBitOrder sets the bit order used by Bits and Bit.
+func (e *Encoder) SetBitOrder(order BitOrder) {
+	e.bitOrder = order
+}
+
+// BitPos returns the current position in bits, including bits pending in a partially written byte.
+func (e *Encoder) BitPos() int64 {
+	pos := e.Pos()
+	if pos < 0 {
+		return -1
+	}
+	return pos*8 + int64(e.bitCount)
+}
+
+// Bits writes the low n bits (up to 64) of v.
+// Any byte aligned write that follows pads a partially written byte with zero bits first.
+func (e *Encoder) Bits(v uint64, n int) {
+	c := e.begin("Bits")
+	e.bits(c, v, n)
+	traceWrite(e, c, v)
+}
+
+// Bit writes a single bit.
+func (e *Encoder) Bit(v bool) {
+	c := e.begin("Bit")
+	var bit uint64
+	if v {
+		bit = 1
+	}
+	e.bits(c, bit, 1)
+	traceWrite(e, c, v)
+}
+
+// AlignByte pads a partially written byte with zero bits and writes it.
+func (e *Encoder) AlignByte() {
+	if e.bitCount == 0 {
+		return
+	}
+	c := e.begin("AlignByte")
+	e.flushBits(c)
+	traceWrite[interface{}](e, c, nil)
+}
+
+// bits writes the low n bits of v, writing each byte to the stream once full.
+func (e *Encoder) bits(c call, v uint64, n int) {
+	if n < 0 || n > 64 {
+		e.fail(c, e.Pos(), 0, fmt.Errorf("bit count %d out of range 0-64", n))
+		return
+	}
+	for i := 0; i < n; i++ {
+		var bit byte
+		if e.bitOrder == LSBFirst {
+			bit = byte(v>>i) & 1
+			e.bitCur |= bit << e.bitCount
+		} else {
+			bit = byte(v>>(n-1-i)) & 1
+			e.bitCur |= bit << (7 - e.bitCount)
+		}
+		e.bitCount++
+		if e.bitCount == 8 {
+			e.flushBits(c)
+		}
+	}
+}
+
+// flushBits writes the pending partial byte, if any.
+func (e *Encoder) flushBits(c call) {
+	if e.bitCount == 0 {
+		return
+	}
+	b := e.scratch[:1]
+	b[0] = e.bitCur
+	e.bitCur = 0
+	e.bitCount = 0
+	e.write(c, b)
+}
@@ -0,0 +1,69 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBits(t *testing.T) {
+	tests := []struct {
+		name  string
+		order BitOrder
+		data  []byte
+	}{
+		{name: "msb", order: MSBFirst, data: []byte{0xa1, 0xc0, 0x7f, 0xff, 0x80}},
+		{name: "lsb", order: LSBFirst, data: []byte{0x0d, 0x03, 0x7f, 0xff, 0x01}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := bytes.NewBuffer(nil)
+			enc := NewEncoder(w, binary.LittleEndian)
+			enc.SetBitOrder(tt.order)
+			enc.Bits(5, 3)
+			enc.Bits(1, 5)
+			enc.Bit(true)
+			enc.Bit(true)
+			if enc.BitPos() != 10 {
+				t.Fatalf("BitPos() = %d, want 10", enc.BitPos())
+			}
+			enc.Uint8(0x7f) // pads the pending bits before writing
+			enc.Bits(0x1ff, 9)
+			enc.AlignByte()
+			if enc.Error() != nil {
+				t.Fatalf("encode: %v", enc.Error())
+			}
+			if !bytes.Equal(w.Bytes(), tt.data) {
+				t.Fatalf("encoded % x, want % x", w.Bytes(), tt.data)
+			}
+
+			dec := NewDecoder(bytes.NewReader(tt.data), binary.LittleEndian)
+			dec.SetBitOrder(tt.order)
+			if v := dec.Bits(3); v != 5 {
+				t.Fatalf("Bits(3) = %d, want 5", v)
+			}
+			if v := dec.Bits(5); v != 1 {
+				t.Fatalf("Bits(5) = %d, want 1", v)
+			}
+			if !dec.Bit() || !dec.Bit() {
+				t.Fatalf("Bit() = false, want true")
+			}
+			if dec.BitPos() != 10 || dec.Pos() != 2 {
+				t.Fatalf("BitPos() = %d, Pos() = %d, want 10 and 2", dec.BitPos(), dec.Pos())
+			}
+			if v := dec.Uint8(); v != 0x7f {
+				t.Fatalf("Uint8() = %#x, want 0x7f", v)
+			}
+			if v := dec.Bits(9); v != 0x1ff {
+				t.Fatalf("Bits(9) = %#x, want 0x1ff", v)
+			}
+			dec.AlignByte()
+			dec.Bits(1)
+			if !errors.Is(dec.Error(), io.EOF) {
+				t.Fatalf("Error() = %v, want io.EOF", dec.Error())
+			}
+		})
+	}
+}
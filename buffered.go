@@ -0,0 +1,93 @@
+package encdec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// NewBufferedEncoder returns an Encoder that collects writes in a buffer and passes them to w in large blocks,
+// for writers where each Write is costly, such as files and network connections.
+// Flush must be called when done. Errors from w are recorded when the buffer is written out, including by Flush.
+func NewBufferedEncoder(w io.Writer, order binary.ByteOrder) *Encoder {
+	e := NewEncoder(w, order)
+	e.bw = bufio.NewWriter(w)
+	return e
+}
+
+// NewBytesEncoder returns an Encoder writing to memory, whose output is returned by Encoded.
+// Placeholders are patched in place, as with any io.WriteSeeker.
+func NewBytesEncoder(order binary.ByteOrder) *Encoder {
+	return NewEncoder(&memory{}, order)
+}
+
+// Flush writes out any data buffered by an Encoder returned by NewBufferedEncoder, returning any failure.
+// Data held until a placeholder is set, on writers that cannot seek, is written out by Set instead,
+// and Flush records a failure while any such placeholder is unset.
+func (e *Encoder) Flush() error {
+	if e.bw == nil && e.unresolved == 0 {
+		return nil
+	}
+	c := e.begin("Flush")
+	if e.bw != nil {
+		pos := e.pos - int64(e.hold.Len()) - int64(e.bw.Buffered())
+		size := e.bw.Buffered()
+		err := e.bw.Flush()
+		if err != nil {
+			e.fail(c, pos, size, err)
+			return e.lastError
+		}
+	}
+	if e.unresolved > 0 {
+		e.fail(c, e.holdPos, e.hold.Len(), fmt.Errorf("%d placeholders unset, %d bytes held", e.unresolved, e.hold.Len()))
+		return e.lastError
+	}
+	return nil
+}
+
+// Len returns the number of bytes written since the Encoder was created,
+// counting data still buffered or held for placeholders.
+func (e *Encoder) Len() int64 {
+	return e.pos - e.start
+}
+
+// Encoded returns everything written by an Encoder returned by NewBytesEncoder, or by an Encoder writing to a
+// *bytes.Buffer, flushing it first if it is buffered. It returns nil for other Encoders.
+// Data held for an unset placeholder is not included, and a failure is recorded as by Flush.
+// The result aliases the Encoder's memory and is only valid until the next write.
+func (e *Encoder) Encoded() []byte {
+	e.Flush()
+	switch w := e.w.(type) {
+	case *memory:
+		return w.b
+	case *bytes.Buffer:
+		return w.Bytes()
+	}
+	return nil
+}
+
+// out returns where written data goes: the buffer of an Encoder returned by NewBufferedEncoder, or the underlying writer.
+func (e *Encoder) out() io.Writer {
+	if e.bw != nil {
+		return e.bw
+	}
+	return e.w
+}
+
+// Write writes p at the current position, overwriting existing bytes and growing the slice as needed.
+func (m *memory) Write(p []byte) (int, error) {
+	end := m.off + int64(len(p))
+	if end > int64(len(m.b)) {
+		if end > int64(cap(m.b)) {
+			b := make([]byte, len(m.b), 2*end)
+			copy(b, m.b)
+			m.b = b
+		}
+		m.b = m.b[:end]
+	}
+	copy(m.b[m.off:], p)
+	m.off = end
+	return len(p), nil
+}
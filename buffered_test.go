@@ -0,0 +1,113 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// countWriter counts the Write calls made to it.
+type countWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestBufferedEncoder(t *testing.T) {
+	w := &countWriter{}
+	enc := NewBufferedEncoder(w, binary.LittleEndian)
+	for i := 0; i < 100; i++ {
+		enc.Uint32(uint32(i))
+	}
+	if w.Len() != 0 || enc.Pos() != 400 || enc.Len() != 400 {
+		t.Fatalf("before Flush: %d bytes written, Pos() = %d, Len() = %d, want 0, 400, 400", w.Len(), enc.Pos(), enc.Len())
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if w.Len() != 400 || w.writes != 1 {
+		t.Fatalf("after Flush: %d bytes in %d writes, want 400 in 1", w.Len(), w.writes)
+	}
+
+	enc = NewBufferedEncoder(failWriter{}, binary.LittleEndian)
+	enc.Uint16(1)
+	if enc.Error() != nil {
+		t.Fatalf("buffered write failed early: %v", enc.Error())
+	}
+	err := enc.Flush()
+	var encErr *EncodeError
+	if !errors.Is(err, io.ErrShortWrite) || !errors.As(err, &encErr) || encErr.Op != "Flush" || encErr.Offset != 0 || encErr.Size != 2 {
+		t.Fatalf("Flush() = %v, want short write of 2 bytes at 0", err)
+	}
+}
+
+func TestBufferedEncoderPlaceholder(t *testing.T) {
+	var encErr *EncodeError
+	want := []byte{
+		0x08, 0x00, 0x00, 0x00, // size
+		0x0e, 0x00, // offset
+		'p', 'a', 'y', 'l', 'o', 'a', 'd', 0x00,
+		0xff,
+	}
+
+	// memory is patched in place
+	enc := NewBytesEncoder(binary.LittleEndian)
+	writePlaceholderExample(enc)
+	if got := enc.Encoded(); enc.Error() != nil || !bytes.Equal(got, want) {
+		t.Fatalf("NewBytesEncoder got % x, %v, want % x", got, enc.Error(), want)
+	}
+
+	// a buffered file is flushed before patching, and positions include the starting offset
+	f, err := os.Create(filepath.Join(t.TempDir(), "buffered.bin"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer f.Close()
+	f.Write([]byte{0xee, 0xee})
+	enc = NewBufferedEncoder(f, binary.LittleEndian)
+	if enc.Pos() != 2 || enc.Len() != 0 {
+		t.Fatalf("Pos() = %d, Len() = %d, want 2 and 0", enc.Pos(), enc.Len())
+	}
+	size := enc.ReserveUint32()
+	enc.StringZero("payload")
+	enc.Uint8(0xff)
+	size.Set(uint64(enc.Len()))
+	if err = enc.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if want := []byte{0xee, 0xee, 0x0d, 0x00, 0x00, 0x00, 'p', 'a', 'y', 'l', 'o', 'a', 'd', 0x00, 0xff}; !bytes.Equal(got, want) {
+		t.Fatalf("file got % x, want % x", got, want)
+	}
+
+	// a bytes.Buffer is exposed through Encoded too
+	buf := &bytes.Buffer{}
+	enc = NewEncoder(buf, binary.LittleEndian)
+	enc.Uint8(1)
+	if got := enc.Encoded(); !bytes.Equal(got, []byte{1}) {
+		t.Fatalf("Encoded() = % x, want 01", got)
+	}
+
+	// a placeholder never set keeps its data held and fails Flush
+	enc.Uint8(2)
+	enc.Field("size").ReserveUint16()
+	enc.Uint8(3)
+	err = enc.Flush()
+	if !errors.As(err, &encErr) || encErr.Op != "Flush" || encErr.Offset != 2 || encErr.Size != 3 {
+		t.Fatalf("Flush() = %v, want failure on 3 held bytes at 2", err)
+	}
+	if got := enc.Encoded(); !bytes.Equal(got, []byte{1, 2}) || enc.Error() == nil {
+		t.Fatalf("Encoded() = % x, %v, want 01 02 and a failure", got, enc.Error())
+	}
+}
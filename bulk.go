@@ -0,0 +1,316 @@
+package encdec
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// blockSize is the size of the buffer bulk reads and writes move data through.
+const blockSize = 4096
+
+// Int8s returns n int8s.
+func (d *Decoder) Int8s(n int) []int8 {
+	c := d.begin("Int8s")
+	v := decodeBulk(d, c, n, 1, func(b []byte) int8 { return int8(b[0]) })
+	traceRead(d, c, v)
+	return v
+}
+
+// Int8sInto fills dst with int8s.
+func (d *Decoder) Int8sInto(dst []int8) {
+	c := d.begin("Int8sInto")
+	readBlock(d, c, dst, 1, func(b []byte) int8 { return int8(b[0]) })
+	traceReadSlice(d, c, dst)
+}
+
+// Uint16s returns n uint16s.
+func (d *Decoder) Uint16s(n int) []uint16 {
+	c := d.begin("Uint16s")
+	v := decodeBulk(d, c, n, 2, d.order.Uint16)
+	traceRead(d, c, v)
+	return v
+}
+
+// Uint16sInto fills dst with uint16s.
+func (d *Decoder) Uint16sInto(dst []uint16) {
+	c := d.begin("Uint16sInto")
+	readBlock(d, c, dst, 2, d.order.Uint16)
+	traceReadSlice(d, c, dst)
+}
+
+// Int16s returns n int16s.
+func (d *Decoder) Int16s(n int) []int16 {
+	c := d.begin("Int16s")
+	v := decodeBulk(d, c, n, 2, d.getInt16())
+	traceRead(d, c, v)
+	return v
+}
+
+// Int16sInto fills dst with int16s.
+func (d *Decoder) Int16sInto(dst []int16) {
+	c := d.begin("Int16sInto")
+	readBlock(d, c, dst, 2, d.getInt16())
+	traceReadSlice(d, c, dst)
+}
+
+// Uint32s returns n uint32s.
+func (d *Decoder) Uint32s(n int) []uint32 {
+	c := d.begin("Uint32s")
+	v := decodeBulk(d, c, n, 4, d.order.Uint32)
+	traceRead(d, c, v)
+	return v
+}
+
+// Uint32sInto fills dst with uint32s.
+func (d *Decoder) Uint32sInto(dst []uint32) {
+	c := d.begin("Uint32sInto")
+	readBlock(d, c, dst, 4, d.order.Uint32)
+	traceReadSlice(d, c, dst)
+}
+
+// Int32s returns n int32s.
+func (d *Decoder) Int32s(n int) []int32 {
+	c := d.begin("Int32s")
+	v := decodeBulk(d, c, n, 4, d.getInt32())
+	traceRead(d, c, v)
+	return v
+}
+
+// Int32sInto fills dst with int32s.
+func (d *Decoder) Int32sInto(dst []int32) {
+	c := d.begin("Int32sInto")
+	readBlock(d, c, dst, 4, d.getInt32())
+	traceReadSlice(d, c, dst)
+}
+
+// Uint64s returns n uint64s.
+func (d *Decoder) Uint64s(n int) []uint64 {
+	c := d.begin("Uint64s")
+	v := decodeBulk(d, c, n, 8, d.order.Uint64)
+	traceRead(d, c, v)
+	return v
+}
+
+// Uint64sInto fills dst with uint64s.
+func (d *Decoder) Uint64sInto(dst []uint64) {
+	c := d.begin("Uint64sInto")
+	readBlock(d, c, dst, 8, d.order.Uint64)
+	traceReadSlice(d, c, dst)
+}
+
+// Int64s returns n int64s.
+func (d *Decoder) Int64s(n int) []int64 {
+	c := d.begin("Int64s")
+	v := decodeBulk(d, c, n, 8, d.getInt64())
+	traceRead(d, c, v)
+	return v
+}
+
+// Int64sInto fills dst with int64s.
+func (d *Decoder) Int64sInto(dst []int64) {
+	c := d.begin("Int64sInto")
+	readBlock(d, c, dst, 8, d.getInt64())
+	traceReadSlice(d, c, dst)
+}
+
+// Float32s returns n float32s, such as the vertices of a mesh.
+func (d *Decoder) Float32s(n int) []float32 {
+	c := d.begin("Float32s")
+	v := decodeBulk(d, c, n, 4, d.getFloat32())
+	traceRead(d, c, v)
+	return v
+}
+
+// Float32sInto fills dst with float32s.
+func (d *Decoder) Float32sInto(dst []float32) {
+	c := d.begin("Float32sInto")
+	readBlock(d, c, dst, 4, d.getFloat32())
+	traceReadSlice(d, c, dst)
+}
+
+// Float64s returns n float64s.
+func (d *Decoder) Float64s(n int) []float64 {
+	c := d.begin("Float64s")
+	v := decodeBulk(d, c, n, 8, d.getFloat64())
+	traceRead(d, c, v)
+	return v
+}
+
+// Float64sInto fills dst with float64s.
+func (d *Decoder) Float64sInto(dst []float64) {
+	c := d.begin("Float64sInto")
+	readBlock(d, c, dst, 8, d.getFloat64())
+	traceReadSlice(d, c, dst)
+}
+
+func (d *Decoder) getInt16() func([]byte) int16 {
+	order := d.order
+	return func(b []byte) int16 { return int16(order.Uint16(b)) }
+}
+
+func (d *Decoder) getInt32() func([]byte) int32 {
+	order := d.order
+	return func(b []byte) int32 { return int32(order.Uint32(b)) }
+}
+
+func (d *Decoder) getInt64() func([]byte) int64 {
+	order := d.order
+	return func(b []byte) int64 { return int64(order.Uint64(b)) }
+}
+
+func (d *Decoder) getFloat32() func([]byte) float32 {
+	order := d.order
+	return func(b []byte) float32 { return math.Float32frombits(order.Uint32(b)) }
+}
+
+func (d *Decoder) getFloat64() func([]byte) float64 {
+	order := d.order
+	return func(b []byte) float64 { return math.Float64frombits(order.Uint64(b)) }
+}
+
+// decodeBulk allocates and reads n values of size bytes each, after checking the Decoder's Limits.
+func decodeBulk[T any](d *Decoder, c call, n int, size int, get func([]byte) T) []T {
+	pos := d.Pos()
+	if n > maxInt/size {
+		d.fail(c, pos, 0, fmt.Errorf("%w: count %d overflows int", ErrLimitExceeded, n))
+		return nil
+	}
+	if !d.checkAlloc(c, pos, n*size) {
+		return nil
+	}
+	dst := make([]T, n)
+	readBlock(d, c, dst, size, get)
+	return dst
+}
+
+// readBlock fills dst with values of size bytes each, reading through a fixed buffer and converting each with get.
+// Like every byte aligned read, it discards the rest of a partially consumed byte.
+func readBlock[T any](d *Decoder, c call, dst []T, size int, get func([]byte) T) {
+	d.bitLeft = 0
+	if len(dst) == 0 {
+		return
+	}
+	pos := d.Pos()
+	per := blockSize / size
+	if per > len(dst) {
+		per = len(dst)
+	}
+	buf := make([]byte, per*size)
+	r := d.reader()
+	for i := 0; i < len(dst); i += per {
+		k := len(dst) - i
+		if k > per {
+			k = per
+		}
+		b := buf[:k*size]
+		_, err := io.ReadFull(r, b)
+		if err != nil {
+			d.fail(c, pos, len(dst)*size, err)
+			return
+		}
+		for j := 0; j < k; j++ {
+			dst[i+j] = get(b[j*size:])
+		}
+	}
+}
+
+// Int8s writes int8s.
+func (e *Encoder) Int8s(v []int8) {
+	c := e.begin("Int8s")
+	writeBlock(e, c, v, 1, func(b []byte, x int8) { b[0] = byte(x) })
+	traceWriteSlice(e, c, v)
+}
+
+// Uint16s writes uint16s.
+func (e *Encoder) Uint16s(v []uint16) {
+	c := e.begin("Uint16s")
+	writeBlock(e, c, v, 2, e.order.PutUint16)
+	traceWriteSlice(e, c, v)
+}
+
+// Int16s writes int16s.
+func (e *Encoder) Int16s(v []int16) {
+	c := e.begin("Int16s")
+	order := e.order
+	writeBlock(e, c, v, 2, func(b []byte, x int16) { order.PutUint16(b, uint16(x)) })
+	traceWriteSlice(e, c, v)
+}
+
+// Uint32s writes uint32s.
+func (e *Encoder) Uint32s(v []uint32) {
+	c := e.begin("Uint32s")
+	writeBlock(e, c, v, 4, e.order.PutUint32)
+	traceWriteSlice(e, c, v)
+}
+
+// Int32s writes int32s.
+func (e *Encoder) Int32s(v []int32) {
+	c := e.begin("Int32s")
+	order := e.order
+	writeBlock(e, c, v, 4, func(b []byte, x int32) { order.PutUint32(b, uint32(x)) })
+	traceWriteSlice(e, c, v)
+}
+
+// Uint64s writes uint64s.
+func (e *Encoder) Uint64s(v []uint64) {
+	c := e.begin("Uint64s")
+	writeBlock(e, c, v, 8, e.order.PutUint64)
+	traceWriteSlice(e, c, v)
+}
+
+// Int64s writes int64s.
+func (e *Encoder) Int64s(v []int64) {
+	c := e.begin("Int64s")
+	order := e.order
+	writeBlock(e, c, v, 8, func(b []byte, x int64) { order.PutUint64(b, uint64(x)) })
+	traceWriteSlice(e, c, v)
+}
+
+// Float32s writes float32s.
+func (e *Encoder) Float32s(v []float32) {
+	c := e.begin("Float32s")
+	order := e.order
+	writeBlock(e, c, v, 4, func(b []byte, x float32) { order.PutUint32(b, math.Float32bits(x)) })
+	traceWriteSlice(e, c, v)
+}
+
+// Float64s writes float64s.
+func (e *Encoder) Float64s(v []float64) {
+	c := e.begin("Float64s")
+	order := e.order
+	writeBlock(e, c, v, 8, func(b []byte, x float64) { order.PutUint64(b, math.Float64bits(x)) })
+	traceWriteSlice(e, c, v)
+}
+
+// writeBlock writes values of size bytes each, converting each with put into a fixed buffer.
+// Like every byte aligned write, it first writes out a partially written byte.
+func writeBlock[T any](e *Encoder, c call, v []T, size int, put func([]byte, T)) {
+	e.flushBits(c)
+	if len(v) == 0 {
+		return
+	}
+	pos := e.Pos()
+	per := blockSize / size
+	if per > len(v) {
+		per = len(v)
+	}
+	buf := make([]byte, per*size)
+	w := e.writer()
+	for i := 0; i < len(v); i += per {
+		k := len(v) - i
+		if k > per {
+			k = per
+		}
+		b := buf[:k*size]
+		for j := 0; j < k; j++ {
+			put(b[j*size:], v[i+j])
+		}
+		n, err := w.Write(b)
+		e.pos += int64(n)
+		if err != nil {
+			e.fail(c, pos, len(v)*size, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBulk(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		i8 := []int8{-1, 2}
+		u16 := []uint16{1, 0xfffe}
+		i16 := []int16{-2, 3}
+		u32 := []uint32{4, 0xfffffffd}
+		i32 := []int32{-5, 6}
+		u64 := []uint64{7, math.MaxUint64}
+		i64 := []int64{-8, math.MinInt64}
+		f32 := make([]float32, 3000) // spans more than one block
+		for i := range f32 {
+			f32[i] = float32(i) / 3
+		}
+		f64 := []float64{math.Pi, -1}
+
+		buf := &bytes.Buffer{}
+		enc := NewEncoder(buf, order)
+		enc.Int8s(i8)
+		enc.Uint16s(u16)
+		enc.Int16s(i16)
+		enc.Uint32s(u32)
+		enc.Int32s(i32)
+		enc.Uint64s(u64)
+		enc.Int64s(i64)
+		enc.Float32s(f32)
+		enc.Float64s(f64)
+		if enc.Error() != nil {
+			t.Fatalf("%v: encode: %v", order, enc.Error())
+		}
+
+		// the bulk writers must match element by element writes
+		want := &bytes.Buffer{}
+		for _, v := range []interface{}{i8, u16, i16, u32, i32, u64, i64, f32, f64} {
+			binary.Write(want, order, v)
+		}
+		if !bytes.Equal(buf.Bytes(), want.Bytes()) {
+			t.Fatalf("%v: bulk writes differ from binary.Write", order)
+		}
+
+		dec := NewDecoder(bytes.NewReader(buf.Bytes()), order)
+		got := []interface{}{dec.Int8s(2), dec.Uint16s(2), dec.Int16s(2), dec.Uint32s(2), dec.Int32s(2), dec.Uint64s(2), dec.Int64s(2)}
+		gotF32 := make([]float32, len(f32))
+		dec.Float32sInto(gotF32)
+		gotF64 := dec.Float64s(2)
+		if dec.Error() != nil {
+			t.Fatalf("%v: decode: %v", order, dec.Error())
+		}
+		if !reflect.DeepEqual(got, []interface{}{i8, u16, i16, u32, i32, u64, i64}) || !reflect.DeepEqual(gotF32, f32) || !reflect.DeepEqual(gotF64, f64) {
+			t.Fatalf("%v: decoded values differ", order)
+		}
+	}
+}
+
+func TestBulkErrors(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader([]byte{0x01, 0x00, 0x02}), binary.LittleEndian)
+	dec.Field("indices").Uint16s(2)
+	var decErr *DecodeError
+	if !errors.Is(dec.Error(), io.ErrUnexpectedEOF) || !errors.As(dec.Error(), &decErr) || decErr.Field != "indices" || decErr.Size != 4 {
+		t.Fatalf("Error() = %v, want indices unexpected EOF", dec.Error())
+	}
+
+	dec = NewDecoder(bytes.NewReader(make([]byte, 64)), binary.LittleEndian)
+	dec.SetLimits(Limits{MaxAlloc: 16})
+	if v := dec.Float32s(5); v != nil || !errors.Is(dec.Error(), ErrLimitExceeded) {
+		t.Fatalf("Float32s(5) = %v, %v, want limit exceeded", v, dec.Error())
+	}
+	if dec.Pos() != 0 {
+		t.Fatalf("Pos() = %d after refused read, want 0", dec.Pos())
+	}
+}
+
+func BenchmarkFloat32s(b *testing.B) {
+	data := make([]byte, 4*100000)
+	dst := make([]float32, 100000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	r := bytes.NewReader(data)
+	dec := NewDecoder(r, binary.LittleEndian)
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		dec.Float32sInto(dst)
+	}
+}
+
+func BenchmarkFloat32PerElement(b *testing.B) {
+	data := make([]byte, 4*100000)
+	dst := make([]float32, 100000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	r := bytes.NewReader(data)
+	dec := NewDecoder(r, binary.LittleEndian)
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		for j := range dst {
+			dst[j] = dec.Float32()
+		}
+	}
+}
+
+func TestBulkTraceCopies(t *testing.T) {
+	// traced slices do not change when the caller reuses them
+	data := []byte{0x01, 0x00, 0x02, 0x00, 0xaa, 0xbb}
+	dec := NewBytesDecoder(data, binary.LittleEndian)
+	dec.SetDebugMode(true)
+	dec.SetZeroCopy(true)
+	dst := make([]uint16, 2)
+	dec.Uint16sInto(dst)
+	b := dec.Bytes(2)
+	dst[0] = 9
+	b[0] = 9
+	entries := dec.Trace()
+	if v := entries[0].Value.([]uint16); v[0] != 1 {
+		t.Fatalf("Uint16sInto traced %v after the caller changed dst", v)
+	}
+	if v := entries[1].Value.([]byte); v[0] != 0xaa {
+		t.Fatalf("zero-copy Bytes traced % x after the caller changed the result", v)
+	}
+
+	enc := NewBytesEncoder(binary.LittleEndian)
+	enc.SetDebugMode(true)
+	src := []uint16{1, 2}
+	raw := []byte{0xaa, 0xbb}
+	enc.Uint16s(src)
+	enc.Bytes(raw)
+	src[0] = 9
+	raw[0] = 9
+	entries = enc.Trace()
+	if v := entries[0].Value.([]uint16); v[0] != 1 {
+		t.Fatalf("Uint16s traced %v after the caller changed its slice", v)
+	}
+	if v := entries[1].Value.([]byte); v[0] != 0xaa {
+		t.Fatalf("Bytes traced % x after the caller changed its slice", v)
+	}
+}
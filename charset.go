@@ -0,0 +1,255 @@
+package encdec
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrUnmappable is the cause recorded when a string holds a character its Charset cannot encode.
+var ErrUnmappable = errors.New("character not representable in charset")
+
+// Charset converts strings between UTF-8 and the text encoding stored in a stream.
+// It is used by the String methods, set for every call with SetCharset or for the next call with WithCharset.
+type Charset interface {
+	// Decode returns b as UTF-8, replacing invalid sequences with U+FFFD.
+	Decode(b []byte) (string, error)
+	// Encode returns the stored form of s. For characters that cannot be stored it returns ErrUnmappable,
+	// along with the rest of the string, each such character written as '?'.
+	Encode(s string) ([]byte, error)
+	// UnitSize returns the size in bytes of a code unit, 1, 2 or 4.
+	// String lengths count code units and zero terminators are one unit wide.
+	UnitSize() int
+}
+
+var (
+	// Raw stores the bytes of a string unchanged. It is the default Charset.
+	Raw Charset = rawCharset{}
+	// UTF16LE is little endian UTF-16.
+	UTF16LE Charset = utf16Charset{order: binary.LittleEndian}
+	// UTF16BE is big endian UTF-16.
+	UTF16BE Charset = utf16Charset{order: binary.BigEndian}
+	// UTF16 is UTF-16 with a byte order mark. Decoding detects the mark, defaulting to little endian without one,
+	// and encoding writes a little endian mark.
+	UTF16 Charset = utf16Charset{order: binary.LittleEndian, hasBOM: true}
+	// Latin1 is ISO 8859-1, mapping each byte to the rune of the same value.
+	Latin1 Charset = singleByteCharset{}
+	// Windows1252 is Windows code page 1252, Latin-1 with printable characters in place of most C1 controls.
+	Windows1252 Charset = singleByteCharset{c1: &windows1252}
+)
+
+// text is how a string is stored: its Charset, and the Cipher set for it by WithCipher, if any.
+type text struct {
+	charset Charset
+	cipher  Cipher
+}
+
+// decipher returns the stored bytes b of a string with the cipher removed.
+// b is copied first, as it may alias memory read without copying.
+func (t text) decipher(b []byte) []byte {
+	if t.cipher == nil {
+		return b
+	}
+	b = append([]byte(nil), b...)
+	t.cipher.Decode(b, 0)
+	return b
+}
+
+// encipher returns the stored bytes s of a string, starting off bytes into it, obfuscated with the cipher.
+func (t text) encipher(s string, off int) string {
+	if t.cipher == nil {
+		return s
+	}
+	b := []byte(s)
+	t.cipher.Encode(b, int64(off))
+	return string(b)
+}
+
+// zeroUnits holds the zero terminator of a string, sliced to the unit size of its Charset.
+const zeroUnits = "\x00\x00\x00\x00"
+
+// SetCharset sets the Charset used by the String methods, Raw by default.
+func (d *Decoder) SetCharset(cs Charset) {
+	d.charset = cs
+}
+
+// Charset returns the Charset set by SetCharset.
+func (d *Decoder) Charset() Charset {
+	if d.charset == nil {
+		return Raw
+	}
+	return d.charset
+}
+
+// WithCharset decodes the next read with cs, if it is a string, in place of the Charset set by SetCharset.
+// It returns the decoder so the read can be chained, e.g. dec.WithCharset(encdec.UTF16LE).StringZero().
+func (d *Decoder) WithCharset(cs Charset) *Decoder {
+	d.nextText.charset = cs
+	return d
+}
+
+// takeText returns how the string read about to begin is stored, consuming any settings made by WithCharset and WithCipher.
+func (d *Decoder) takeText() text {
+	t := d.nextText
+	if t.charset == nil {
+		t.charset = d.Charset()
+	}
+	d.nextText = text{}
+	return t
+}
+
+// decodeString converts the stored bytes b of a string read with cs to UTF-8, recording any failure.
+func (d *Decoder) decodeString(c call, cs Charset, b []byte) string {
+	s, err := cs.Decode(b)
+	if err != nil {
+		d.fail(c, d.Pos()-int64(len(b)), len(b), err)
+	}
+	return s
+}
+
+// SetCharset sets the Charset used by the String methods, Raw by default.
+func (e *Encoder) SetCharset(cs Charset) {
+	e.charset = cs
+}
+
+// Charset returns the Charset set by SetCharset.
+func (e *Encoder) Charset() Charset {
+	if e.charset == nil {
+		return Raw
+	}
+	return e.charset
+}
+
+// WithCharset encodes the next write with cs, if it is a string, in place of the Charset set by SetCharset.
+// It returns the encoder so the write can be chained, e.g. enc.WithCharset(encdec.UTF16LE).StringZero(s).
+func (e *Encoder) WithCharset(cs Charset) *Encoder {
+	e.nextText.charset = cs
+	return e
+}
+
+// takeText returns how the string write about to begin is stored, consuming any settings made by WithCharset and WithCipher.
+func (e *Encoder) takeText() text {
+	t := e.nextText
+	if t.charset == nil {
+		t.charset = e.Charset()
+	}
+	e.nextText = text{}
+	return t
+}
+
+// encodeString returns the stored form of s in cs, recording any failure.
+// Raw strings are returned unchanged so they are written without a copy.
+func (e *Encoder) encodeString(c call, cs Charset, s string) string {
+	if cs == Raw {
+		return s
+	}
+	b, err := cs.Encode(s)
+	if err != nil {
+		e.fail(c, e.Pos(), len(b), err)
+	}
+	return string(b)
+}
+
+type rawCharset struct{}
+
+func (rawCharset) Decode(b []byte) (string, error) { return string(b), nil }
+func (rawCharset) Encode(s string) ([]byte, error) { return []byte(s), nil }
+func (rawCharset) UnitSize() int                   { return 1 }
+
+type utf16Charset struct {
+	order  binary.ByteOrder
+	hasBOM bool
+}
+
+func (cs utf16Charset) Decode(b []byte) (string, error) {
+	order := cs.order
+	if cs.hasBOM && len(b) >= 2 {
+		switch {
+		case b[0] == 0xff && b[1] == 0xfe:
+			order, b = binary.LittleEndian, b[2:]
+		case b[0] == 0xfe && b[1] == 0xff:
+			order, b = binary.BigEndian, b[2:]
+		}
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = order.Uint16(b[2*i:])
+	}
+	s := string(utf16.Decode(units))
+	if len(b)%2 != 0 {
+		s += string(utf8.RuneError)
+	}
+	return s, nil
+}
+
+func (cs utf16Charset) Encode(s string) ([]byte, error) {
+	units := utf16.Encode([]rune(s))
+	if cs.hasBOM {
+		units = append([]uint16{0xfeff}, units...)
+	}
+	b := make([]byte, 2*len(units))
+	for i, u := range units {
+		cs.order.PutUint16(b[2*i:], u)
+	}
+	return b, nil
+}
+
+func (utf16Charset) UnitSize() int { return 2 }
+
+// singleByteCharset maps bytes below 0x80 to ASCII and the rest to runes of the same value,
+// except bytes 0x80-0x9F which are looked up in c1 when set.
+type singleByteCharset struct {
+	c1 *[32]rune
+}
+
+func (cs singleByteCharset) Decode(b []byte) (string, error) {
+	var sb strings.Builder
+	sb.Grow(len(b))
+	for _, c := range b {
+		r := rune(c)
+		if cs.c1 != nil && c >= 0x80 && c < 0xa0 {
+			r = cs.c1[c-0x80]
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String(), nil
+}
+
+func (cs singleByteCharset) Encode(s string) ([]byte, error) {
+	var err error
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		c, ok := cs.encodeRune(r)
+		if !ok {
+			c, err = '?', ErrUnmappable
+		}
+		b = append(b, c)
+	}
+	return b, err
+}
+
+func (cs singleByteCharset) encodeRune(r rune) (byte, bool) {
+	if r < 0x80 || (r < 0x100 && (cs.c1 == nil || r >= 0xa0)) {
+		return byte(r), true
+	}
+	if cs.c1 != nil {
+		for i, c1 := range cs.c1 {
+			if c1 == r {
+				return byte(0x80 + i), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (singleByteCharset) UnitSize() int { return 1 }
+
+// windows1252 maps bytes 0x80-0x9F of code page 1252. The five unassigned bytes map to their C1 controls.
+var windows1252 = [32]rune{
+	0x20ac, 0x0081, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+	0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008d, 0x017d, 0x008f,
+	0x0090, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+	0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0x009d, 0x017e, 0x0178,
+}
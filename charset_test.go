@@ -0,0 +1,148 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCharset(t *testing.T) {
+	tests := []struct {
+		name   string
+		cs     Charset
+		s      string
+		stored []byte
+	}{
+		{"raw", Raw, "héllo", []byte("héllo")},
+		{"utf16le", UTF16LE, "Aé😀", []byte{0x41, 0x00, 0xe9, 0x00, 0x3d, 0xd8, 0x00, 0xde}},
+		{"utf16be", UTF16BE, "Aé", []byte{0x00, 0x41, 0x00, 0xe9}},
+		{"utf16", UTF16, "A", []byte{0xff, 0xfe, 0x41, 0x00}},
+		{"latin1", Latin1, "café\u0085", []byte{'c', 'a', 'f', 0xe9, 0x85}},
+		{"windows1252", Windows1252, "€‚Ÿé\u0081", []byte{0x80, 0x82, 0x9f, 0xe9, 0x81}},
+		{"shiftjis", ShiftJIS, "A日本語ｶﾅ①", []byte{'A', 0x93, 0xfa, 0x96, 0x7b, 0x8c, 0xea, 0xb6, 0xc5, 0x87, 0x40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.cs.Encode(tt.s)
+			if err != nil || !bytes.Equal(b, tt.stored) {
+				t.Fatalf("Encode(%q) = % x, %v, want % x", tt.s, b, err, tt.stored)
+			}
+			s, err := tt.cs.Decode(tt.stored)
+			if err != nil || s != tt.s {
+				t.Fatalf("Decode(% x) = %q, %v, want %q", tt.stored, s, err, tt.s)
+			}
+		})
+	}
+}
+
+func TestCharsetInvalid(t *testing.T) {
+	if s, _ := UTF16.Decode([]byte{0xfe, 0xff, 0x00, 0x41, 0x00}); s != "A�" {
+		t.Fatalf("UTF16 big endian BOM with odd length = %q", s)
+	}
+	if s, _ := ShiftJIS.Decode([]byte{0x82, 0xa0, 0x85, 0x41, 0x88}); s != "あ�A�" {
+		t.Fatalf("ShiftJIS invalid pairs = %q", s)
+	}
+	for _, cs := range []Charset{Latin1, Windows1252, ShiftJIS} {
+		b, err := cs.Encode("a☃b")
+		if !errors.Is(err, ErrUnmappable) || string(b) != "a?b" {
+			t.Fatalf("Encode unmappable = %q, %v, want a?b and ErrUnmappable", b, err)
+		}
+	}
+}
+
+func TestShiftJISDuplicates(t *testing.T) {
+	// characters with several codes are written as Windows does: JIS X 0208 first, then NEC row 13,
+	// then the IBM extensions, then the NEC-selected IBM extensions
+	for _, tt := range []struct {
+		s    string
+		want []byte
+	}{
+		{"\u2170", []byte{0xfa, 0x40}}, // also 0xEEEF
+		{"\u7e8a", []byte{0xfa, 0x5c}}, // also 0xED40
+		{"\u222a", []byte{0x81, 0xbe}}, // also 0x879B
+		{"\u2252", []byte{0x81, 0xe0}}, // also 0x8790
+		{"\uffe2", []byte{0x81, 0xca}}, // also 0xEEF9 and 0xFA54
+		{"\u2160", []byte{0x87, 0x54}}, // also 0xFA4A
+	} {
+		b, err := ShiftJIS.Encode(tt.s)
+		if err != nil || !bytes.Equal(b, tt.want) {
+			t.Fatalf("Encode(%q) = % x, %v, want % x", tt.s, b, err, tt.want)
+		}
+	}
+}
+
+func TestStringCharset(t *testing.T) {
+	enc := NewBytesEncoder(binary.LittleEndian)
+	enc.SetCharset(UTF16LE)
+	enc.StringZero("hi")
+	enc.StringLenPrefixUint8("hé")
+	enc.StringFixed("abc", 2)
+	enc.WithCharset(ShiftJIS).StringLenPrefixUint16("日本")
+	enc.StringFixed("x", 2)
+	want := []byte{
+		'h', 0, 'i', 0, 0, 0, // double-zero terminated
+		0x02, 'h', 0, 0xe9, 0, // prefix counts units
+		'a', 0, 'b', 0, // cut to 2 units
+		0x04, 0x00, 0x93, 0xfa, 0x96, 0x7b, // per-call Shift-JIS
+		'x', 0, 0, 0, // padded with a zero unit
+	}
+	got := enc.Encoded()
+	if enc.Error() != nil || !bytes.Equal(got, want) {
+		t.Fatalf("encoded % x, %v, want % x", got, enc.Error(), want)
+	}
+
+	dec := NewBytesDecoder(got, binary.LittleEndian)
+	dec.SetCharset(UTF16LE)
+	if s := dec.StringZero(); s != "hi" {
+		t.Fatalf("StringZero() = %q", s)
+	}
+	if s := dec.StringLenPrefixUint8(); s != "hé" {
+		t.Fatalf("StringLenPrefixUint8() = %q", s)
+	}
+	if s := dec.StringFixed(2); s != "ab" {
+		t.Fatalf("StringFixed(2) = %q", s)
+	}
+	if n := dec.WithCharset(ShiftJIS).Uint16(); n != 4 {
+		t.Fatalf("Uint16() = %d", n)
+	}
+	sub := dec.Sub(4)
+	if s := sub.StringFixed(2); s != "\ufa93\u7b96" {
+		t.Fatalf("Sub did not read with the inherited charset, got %q", s)
+	}
+	sub.Close()
+	if s := dec.StringFixed(2); s != "x\x00" || dec.Error() != nil {
+		t.Fatalf("StringFixed(2) = %q, %v", s, dec.Error())
+	}
+
+	enc = NewBytesEncoder(binary.LittleEndian)
+	enc.WithCharset(Latin1).Field("name").StringZero("☃")
+	var encErr *EncodeError
+	if !errors.As(enc.Error(), &encErr) || !errors.Is(encErr, ErrUnmappable) || encErr.Field != "name" {
+		t.Fatalf("unmappable write: %v", enc.Error())
+	}
+	if got := enc.Encoded(); !bytes.Equal(got, []byte{'?', 0}) {
+		t.Fatalf("unmappable write got % x", got)
+	}
+}
+
+func TestStringLenPrefixOverflow(t *testing.T) {
+	// the prefix counts code units, so 255 UTF-16 units fit in a uint8 prefix
+	enc := NewBytesEncoder(binary.LittleEndian)
+	enc.WithCharset(UTF16LE).StringLenPrefixUint8(strings.Repeat("a", 0xff))
+	if enc.Error() != nil || enc.Len() != 1+2*0xff {
+		t.Fatalf("255 units wrote %d bytes, %v", enc.Len(), enc.Error())
+	}
+
+	// one more unit than the prefix can count records a failure and writes nothing
+	enc.Field("name").WithCharset(UTF16LE).StringLenPrefixUint8(strings.Repeat("a", 0x100))
+	var encErr *EncodeError
+	if !errors.As(enc.Error(), &encErr) || encErr.Field != "name" || encErr.Op != "StringLenPrefixUint8" || enc.Len() != 1+2*0xff {
+		t.Fatalf("Error() = %v after %d bytes, want name count overflow and nothing written", enc.Error(), enc.Len())
+	}
+	enc.Field("title").StringLenPrefixUint16(strings.Repeat("a", 0x10000))
+	if !errors.As(enc.LastError(), &encErr) || encErr.Field != "title" || encErr.Offset != 1+2*0xff || enc.Len() != 1+2*0xff {
+		t.Fatalf("LastError() = %v after %d bytes, want title count overflow and nothing written", enc.LastError(), enc.Len())
+	}
+}
@@ -0,0 +1,167 @@
+package encdec
+
+import (
+	"io"
+)
+
+// Cipher obfuscates stored bytes in place, such as string tables XORed with a repeating key.
+// off is the position of b[0] from the start of the obfuscated data, so keyed ciphers line up however the data is split into reads.
+// Set one for a region of the stream with SetCipher, or for the next string with WithCipher.
+type Cipher interface {
+	// Decode restores the original bytes of b.
+	Decode(b []byte, off int64)
+	// Encode obfuscates b, reversing Decode.
+	Encode(b []byte, off int64)
+}
+
+// NewXORCipher returns a Cipher that XORs each byte with key, repeating the key from the start of the obfuscated data.
+func NewXORCipher(key []byte) Cipher {
+	return xorCipher{key: append([]byte(nil), key...)}
+}
+
+type xorCipher struct {
+	key []byte
+}
+
+func (x xorCipher) Decode(b []byte, off int64) {
+	if len(x.key) == 0 {
+		return
+	}
+	k := int(off % int64(len(x.key)))
+	for i := range b {
+		b[i] ^= x.key[k]
+		k++
+		if k == len(x.key) {
+			k = 0
+		}
+	}
+}
+
+func (x xorCipher) Encode(b []byte, off int64) {
+	x.Decode(b, off)
+}
+
+// NewByteMapCipher returns a Cipher that replaces each stored byte v with table[v] when decoding.
+// Encoding reverses the lookup, so table should be a permutation; bytes it never produces encode as 0.
+func NewByteMapCipher(table [256]byte) Cipher {
+	m := &byteMapCipher{decode: table}
+	seen := [256]bool{}
+	for i, v := range table {
+		if !seen[v] {
+			m.encode[v] = byte(i)
+			seen[v] = true
+		}
+	}
+	return m
+}
+
+type byteMapCipher struct {
+	decode [256]byte
+	encode [256]byte
+}
+
+func (m *byteMapCipher) Decode(b []byte, off int64) {
+	for i, v := range b {
+		b[i] = m.decode[v]
+	}
+}
+
+func (m *byteMapCipher) Encode(b []byte, off int64) {
+	for i, v := range b {
+		b[i] = m.encode[v]
+	}
+}
+
+// SetCipher decodes everything read from here on with ci, its offsets counted from the current position,
+// typically for a section returned by Sub. Zero copy reads are copied while a Cipher is set.
+// SetCipher(nil) ends the region.
+func (d *Decoder) SetCipher(ci Cipher) {
+	if s, ok := d.r.(*cipherStream); ok {
+		d.r = s.r
+	}
+	if ci == nil {
+		return
+	}
+	pos, err := d.r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		c := d.begin("SetCipher")
+		d.fail(c, -1, 0, err)
+		return
+	}
+	d.r = &cipherStream{r: d.r, ci: ci, pos: pos, start: pos}
+}
+
+// WithCipher decodes the next read with ci, if it is a string, with offsets counted from the start of the string.
+// The whole field is decoded, including any zero terminator or padding, but not a length prefix.
+// It returns the decoder so the read can be chained, e.g. dec.WithCipher(key).StringZero().
+func (d *Decoder) WithCipher(ci Cipher) *Decoder {
+	d.nextText.cipher = ci
+	return d
+}
+
+// cipherStream decodes a stream read through it with a Cipher.
+type cipherStream struct {
+	r     io.ReadSeeker
+	ci    Cipher
+	pos   int64
+	start int64
+}
+
+// Read reads from the underlying stream and decodes the bytes read.
+func (s *cipherStream) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.ci.Decode(p[:n], s.pos-s.start)
+	s.pos += int64(n)
+	return n, err
+}
+
+// Seek seeks the underlying stream.
+func (s *cipherStream) Seek(offset int64, whence int) (int64, error) {
+	pos, err := s.r.Seek(offset, whence)
+	if err == nil {
+		s.pos = pos
+	}
+	return pos, err
+}
+
+// size returns the length of the underlying stream.
+func (s *cipherStream) size() (int64, bool) {
+	n := streamLen(s.r)
+	return n, n >= 0
+}
+
+// peek returns a decoded copy of the next n bytes of an underlying stream that canPeek.
+func (s *cipherStream) peek(n int) ([]byte, error) {
+	b, err := s.r.(peeker).peek(n)
+	out := append([]byte(nil), b...)
+	s.ci.Decode(out, s.pos-s.start)
+	return out, err
+}
+
+// SetCipher encodes everything written from here on with ci, its offsets counted from the current position,
+// including placeholders reserved while it is set. SetCipher(nil) ends the region.
+func (e *Encoder) SetCipher(ci Cipher) {
+	e.cipher = ci
+	e.cipherStart = e.pos
+}
+
+// WithCipher encodes the next write with ci, if it is a string, with offsets counted from the start of the string.
+// The whole field is encoded, including any zero terminator or padding, but not a length prefix.
+// It returns the encoder so the write can be chained, e.g. enc.WithCipher(key).StringZero(s).
+func (e *Encoder) WithCipher(ci Cipher) *Encoder {
+	e.nextText.cipher = ci
+	return e
+}
+
+// cipherWriter encodes writes with the Encoder's Cipher, leaving the caller's bytes untouched.
+type cipherWriter struct {
+	e *Encoder
+	w io.Writer
+}
+
+// Write encodes a copy of p, which starts at the Encoder's current position, and writes it.
+func (w cipherWriter) Write(p []byte) (int, error) {
+	w.e.cipherBuf = append(w.e.cipherBuf[:0], p...)
+	w.e.cipher.Encode(w.e.cipherBuf, w.e.pos-w.e.cipherStart)
+	return w.w.Write(w.e.cipherBuf)
+}
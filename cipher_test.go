@@ -0,0 +1,108 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// wldKey is the XOR key of EverQuest WLD string hash tables.
+var wldKey = []byte{0x95, 0x3a, 0xc5, 0x2a, 0x95, 0x7a, 0x95, 0x6a}
+
+// xorBytes returns b XORed with key, repeating from the start of b.
+func xorBytes(b, key []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[i] = b[i] ^ key[i%len(key)]
+	}
+	return out
+}
+
+func TestCipherRegion(t *testing.T) {
+	table := []byte("floor\x00wall_texture\x00")
+	want := append([]byte{byte(len(table)), 0, 0, 0}, xorBytes(table, wldKey)...)
+	want = append(want, 0xff)
+
+	for _, enc := range []*Encoder{NewBytesEncoder(binary.LittleEndian), NewEncoder(&bytes.Buffer{}, binary.LittleEndian)} {
+		size := enc.ReserveUint32()
+		enc.SetCipher(NewXORCipher(wldKey))
+		enc.StringZero("floor")
+		enc.StringZero("wall_texture")
+		enc.SetCipher(nil)
+		size.Set(uint64(enc.Len() - 4))
+		enc.Uint8(0xff)
+		if got := enc.Encoded(); enc.Error() != nil || !bytes.Equal(got, want) {
+			t.Fatalf("encoded % x, %v, want % x", got, enc.Error(), want)
+		}
+	}
+
+	data := append([]byte(nil), want...)
+	for _, dec := range []*Decoder{NewBytesDecoder(data, binary.LittleEndian), NewReaderDecoder(bytes.NewReader(data), binary.LittleEndian)} {
+		dec.SetZeroCopy(true)
+		sub := dec.Sub(int64(dec.Uint32()))
+		sub.SetCipher(NewXORCipher(wldKey))
+		if b := sub.Peek(2); !bytes.Equal(b, []byte("fl")) {
+			t.Fatalf("Peek(2) = %q", b)
+		}
+		names := SliceUntilEnd(sub, (*Decoder).StringZero)
+		if err := sub.Close(); err != nil || len(names) != 2 || names[0] != "floor" || names[1] != "wall_texture" {
+			t.Fatalf("names = %q, %v", names, err)
+		}
+		if v := dec.Uint8(); v != 0xff || dec.Error() != nil {
+			t.Fatalf("after region Uint8() = %#x, %v", v, dec.Error())
+		}
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("zero copy decoding modified the input: % x", data)
+	}
+}
+
+func TestCipherString(t *testing.T) {
+	key := NewXORCipher([]byte{0x11, 0x22})
+	enc := NewBytesEncoder(binary.LittleEndian)
+	enc.WithCipher(key).StringLenPrefixUint8("abc")
+	enc.WithCipher(key).StringZero("ab")
+	enc.WithCipher(key).WithCharset(UTF16LE).StringFixed("a", 2)
+	enc.StringZero("x")
+	want := []byte{
+		0x03, 'a' ^ 0x11, 'b' ^ 0x22, 'c' ^ 0x11, // the prefix is not encoded
+		'a' ^ 0x11, 'b' ^ 0x22, 0x11, // the terminator is
+		'a' ^ 0x11, 0x22, 0x11, 0x22,
+		'x', 0x00, // the next string is plain
+	}
+	got := enc.Encoded()
+	if enc.Error() != nil || !bytes.Equal(got, want) {
+		t.Fatalf("encoded % x, %v, want % x", got, enc.Error(), want)
+	}
+
+	dec := NewBytesDecoder(got, binary.LittleEndian)
+	if s := dec.WithCipher(key).StringLenPrefixUint8(); s != "abc" {
+		t.Fatalf("StringLenPrefixUint8() = %q", s)
+	}
+	if s := dec.WithCipher(key).StringZero(); s != "ab" {
+		t.Fatalf("StringZero() = %q", s)
+	}
+	if s := dec.WithCipher(key).WithCharset(UTF16LE).StringPadded(2, PadZeroStrict); s != "a" {
+		t.Fatalf("StringPadded() = %q, %v", s, dec.Error())
+	}
+	if s := dec.StringZero(); s != "x" || dec.Error() != nil {
+		t.Fatalf("StringZero() = %q, %v", s, dec.Error())
+	}
+}
+
+func TestByteMapCipher(t *testing.T) {
+	var table [256]byte
+	for i := range table {
+		table[i] = byte(i + 1)
+	}
+	m := NewByteMapCipher(table)
+	b := []byte{0x00, 0x41, 0xff}
+	m.Decode(b, 0)
+	if !bytes.Equal(b, []byte{0x01, 0x42, 0x00}) {
+		t.Fatalf("Decode = % x", b)
+	}
+	m.Encode(b, 0)
+	if !bytes.Equal(b, []byte{0x00, 0x41, 0xff}) {
+		t.Fatalf("Encode = % x", b)
+	}
+}
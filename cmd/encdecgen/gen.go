@@ -0,0 +1,563 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/xackery/encdec/internal/tag"
+)
+
+// generator emits Decode and Encode methods for struct types of a single package.
+type generator struct {
+	pkg     string
+	structs map[string]*ast.StructType
+	fset    *token.FileSet
+}
+
+// newGenerator returns a generator for the struct types declared in files of package pkg.
+func newGenerator(fset *token.FileSet, pkg string, files []*ast.File) *generator {
+	g := &generator{pkg: pkg, fset: fset, structs: map[string]*ast.StructType{}}
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			ts, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if ok {
+				g.structs[ts.Name.Name] = st
+			}
+			return false
+		})
+	}
+	return g
+}
+
+// writer accumulates indented lines of a method body.
+type writer struct {
+	buf      bytes.Buffer
+	indent   int
+	depth    int
+	setOrder bool
+	order    string // byte order set by the enclosing le or be option, empty for the order at entry
+}
+
+func (w *writer) line(format string, args ...interface{}) {
+	w.buf.WriteString(strings.Repeat("\t", w.indent))
+	fmt.Fprintf(&w.buf, format, args...)
+	w.buf.WriteByte('\n')
+}
+
+// pushOrder switches to the byte order expression order of an le or be option,
+// returning the enclosing order to restore after the field, like Struct does.
+func (w *writer) pushOrder(order string) string {
+	w.setOrder = true
+	outer := w.order
+	if outer == "" {
+		outer = "order"
+	}
+	w.order = order
+	return outer
+}
+
+// index returns the loop index variable for the current loop depth.
+func (w *writer) index() string {
+	names := []string{"i", "j", "k"}
+	if w.depth < len(names) {
+		return names[w.depth]
+	}
+	return "i" + strconv.Itoa(w.depth)
+}
+
+// generate returns the formatted source of Decode and Encode methods for each of types.
+func (g *generator) generate(types []string) ([]byte, error) {
+	methods := bytes.Buffer{}
+	usesBinary := false
+	for _, name := range types {
+		st, ok := g.structs[name]
+		if !ok {
+			return nil, fmt.Errorf("struct type %s not found in package %s", name, g.pkg)
+		}
+
+		dec := &writer{indent: 1}
+		err := g.decodeStruct(dec, "def", st)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		enc := &writer{indent: 1}
+		err = g.encodeStruct(enc, "def", st)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		usesBinary = usesBinary || dec.setOrder || enc.setOrder
+
+		fmt.Fprintf(&methods, "\n// Decode decodes %s from dec.\n", name)
+		fmt.Fprintf(&methods, "func (def *%s) Decode(dec *encdec.Decoder) error {\n", name)
+		if dec.setOrder {
+			methods.WriteString("\torder := dec.Order()\n")
+		}
+		methods.Write(dec.buf.Bytes())
+		methods.WriteString("\n\tif dec.Error() != nil {\n")
+		fmt.Fprintf(&methods, "\t\treturn fmt.Errorf(\"decode %s: %%w\", dec.Error())\n", name)
+		methods.WriteString("\t}\n\treturn nil\n}\n")
+
+		fmt.Fprintf(&methods, "\n// Encode encodes %s to enc.\n", name)
+		fmt.Fprintf(&methods, "func (def *%s) Encode(enc *encdec.Encoder) error {\n", name)
+		if enc.setOrder {
+			methods.WriteString("\torder := enc.Order()\n")
+		}
+		methods.Write(enc.buf.Bytes())
+		methods.WriteString("\n\tif enc.Error() != nil {\n")
+		fmt.Fprintf(&methods, "\t\treturn fmt.Errorf(\"encode %s: %%w\", enc.Error())\n", name)
+		methods.WriteString("\t}\n\treturn nil\n}\n")
+	}
+
+	out := bytes.Buffer{}
+	out.WriteString("// Code generated by encdecgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", g.pkg)
+	if usesBinary {
+		out.WriteString("\t\"encoding/binary\"\n")
+	}
+	out.WriteString("\t\"fmt\"\n\n\t\"github.com/xackery/encdec\"\n)\n")
+	out.Write(methods.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format: %w", err)
+	}
+	return src, nil
+}
+
+// field is a parsed struct field.
+type field struct {
+	name string
+	typ  ast.Expr
+	tag  tag.Tag
+}
+
+// fields returns the exported fields of st with their parsed tags, skipping fields tagged "-" like Struct does.
+func (g *generator) fields(st *ast.StructType) ([]field, error) {
+	fields := []field{}
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			return nil, fmt.Errorf("embedded field %s is not supported", g.expr(f.Type))
+		}
+		t := tag.Tag{}
+		if f.Tag != nil {
+			raw, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Names[0].Name, err)
+			}
+			t, err = tag.Parse(reflect.StructTag(raw).Get("encdec"))
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Names[0].Name, err)
+			}
+		}
+		if t.Skip {
+			continue
+		}
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+			fields = append(fields, field{name: name.Name, typ: f.Type, tag: t})
+		}
+	}
+	return fields, nil
+}
+
+// decodeStruct emits a decode of each field of st into the struct expression target.
+func (g *generator) decodeStruct(w *writer, target string, st *ast.StructType) error {
+	fields, err := g.fields(st)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		err = g.decodeValue(w, target+"."+f.name, f.typ, f.tag, target)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// decodeValue emits a decode into target of type typ, looking up len=Field counts in owner.
+func (g *generator) decodeValue(w *writer, target string, typ ast.Expr, t tag.Tag, owner string) error {
+	if t.Order != "" {
+		outer := w.pushOrder(orderExpr(t.Order))
+		w.line("dec.SetOrder(%s)", w.order)
+		defer func() {
+			w.order = outer
+			w.line("dec.SetOrder(%s)", outer)
+		}()
+	}
+	switch typ := typ.(type) {
+	case *ast.Ident:
+		if st, ok := g.structs[typ.Name]; ok && t.Kind == "" {
+			return g.decodeStruct(w, target, st)
+		}
+		value, err := g.decodeExpr(w, target, typ.Name, t, owner)
+		if err != nil {
+			return err
+		}
+		w.line("%s = %s", target, value)
+		return nil
+	case *ast.ArrayType:
+		elemTag := tag.Tag{Kind: t.Kind, Zero: t.Zero}
+		if typ.Len != nil {
+			if isByteElem(typ.Elt, t) {
+				w.line("copy(%s[:], dec.Bytes(len(%s)))", target, target)
+				return nil
+			}
+			index := w.index()
+			w.line("for %s := range %s {", index, target)
+			w.indent++
+			w.depth++
+			err := g.decodeValue(w, target+"["+index+"]", typ.Elt, elemTag, owner)
+			w.depth--
+			w.indent--
+			w.line("}")
+			return err
+		}
+		count, err := g.decodeCount(w, target, t, owner)
+		if err != nil {
+			return err
+		}
+		if isByteElem(typ.Elt, t) {
+			w.line("%s = dec.Bytes(%s)", target, count)
+			return nil
+		}
+		item := itemName(target)
+		index := w.index()
+		// a fresh slice like Struct, so decoding into a reused value does not keep its elements
+		w.line("%s = %s{}", target, g.expr(typ))
+		// stop at the first error like Struct, so a corrupt count does not append zero elements until it is reached
+		w.line("for %s := 0; %s < %s && dec.Error() == nil; %s++ {", index, index, count, index)
+		w.indent++
+		w.depth++
+		ident, isIdent := typ.Elt.(*ast.Ident)
+		switch {
+		case isIdent && g.structs[ident.Name] != nil && elemTag.Kind == "":
+			w.line("%s := %s{}", item, ident.Name)
+			err = g.decodeValue(w, item, typ.Elt, elemTag, owner)
+		case isIdent:
+			var value string
+			value, err = g.decodeExpr(w, item, ident.Name, elemTag, owner)
+			w.line("%s := %s", item, value)
+		default:
+			w.line("var %s %s", item, g.expr(typ.Elt))
+			err = g.decodeValue(w, item, typ.Elt, elemTag, owner)
+		}
+		w.line("%s = append(%s, %s)", target, target, item)
+		w.depth--
+		w.indent--
+		w.line("}")
+		return err
+	}
+	return fmt.Errorf("unsupported type %s", g.expr(typ))
+}
+
+// decodeExpr returns the expression decoding target, of the named non-struct type, emitting a read of its count prefix if it has one.
+func (g *generator) decodeExpr(w *writer, target string, typeName string, t tag.Tag, owner string) (string, error) {
+	kind, err := identKind(typeName, t)
+	if err != nil {
+		return "", err
+	}
+	if kind == "str" {
+		value, err := g.decodeString(w, target, t, owner)
+		if err != nil {
+			return "", err
+		}
+		return convert(typeName, "string", value), nil
+	}
+	method, _ := tag.Method(kind)
+	return convert(typeName, tag.GoType(kind), "dec."+method+"()"), nil
+}
+
+// decodeString returns the expression decoding a string described by t.
+func (g *generator) decodeString(w *writer, target string, t tag.Tag, owner string) (string, error) {
+	err := t.CheckString()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case t.Zero:
+		return "dec.StringZero()", nil
+	case t.Prefix == "u8":
+		return "dec.StringLenPrefixUint8()", nil
+	case t.Prefix == "u16":
+		return "dec.StringLenPrefixUint16()", nil
+	case t.Prefix == "u32":
+		return "dec.StringLenPrefixUint32()", nil
+	}
+	count, err := g.decodeCount(w, target, t, owner)
+	if err != nil {
+		return "", err
+	}
+	return "dec.StringFixed(" + count + ")", nil
+}
+
+// decodeCount returns the expression for the length of target, emitting a read of its prefix if it has one.
+func (g *generator) decodeCount(w *writer, target string, t tag.Tag, owner string) (string, error) {
+	if t.Prefix != "" {
+		method, _ := tag.Method(t.Prefix)
+		name := lenName(target)
+		w.line("%s := dec.%s()", name, method)
+		return "int(" + name + ")", nil
+	}
+	return countExpr(t, owner)
+}
+
+// encodeStruct emits an encode of each field of the struct expression target.
+func (g *generator) encodeStruct(w *writer, target string, st *ast.StructType) error {
+	fields, err := g.fields(st)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		err = g.encodeValue(w, target+"."+f.name, f.typ, f.tag, target)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// encodeValue emits an encode of target of type typ, looking up len=Field counts in owner.
+func (g *generator) encodeValue(w *writer, target string, typ ast.Expr, t tag.Tag, owner string) error {
+	if t.Order != "" {
+		outer := w.pushOrder(orderExpr(t.Order))
+		w.line("enc.SetOrder(%s)", w.order)
+		defer func() {
+			w.order = outer
+			w.line("enc.SetOrder(%s)", outer)
+		}()
+	}
+	switch typ := typ.(type) {
+	case *ast.Ident:
+		if st, ok := g.structs[typ.Name]; ok && t.Kind == "" {
+			return g.encodeStruct(w, target, st)
+		}
+		kind, err := identKind(typ.Name, t)
+		if err != nil {
+			return err
+		}
+		if kind == "str" {
+			return g.encodeString(w, convert("string", typ.Name, target), t, owner)
+		}
+		method, _ := tag.Method(kind)
+		w.line("enc.%s(%s)", method, convert(tag.GoType(kind), typ.Name, target))
+		return nil
+	case *ast.ArrayType:
+		elemTag := tag.Tag{Kind: t.Kind, Zero: t.Zero}
+		if typ.Len == nil {
+			return g.encodeSlice(w, target, typ, t, owner)
+		}
+		if isByteElem(typ.Elt, t) {
+			w.line("enc.Bytes(%s[:])", target)
+			return nil
+		}
+		item := itemName(target)
+		w.line("for _, %s := range %s {", item, target)
+		w.indent++
+		w.depth++
+		err := g.encodeValue(w, item, typ.Elt, elemTag, owner)
+		w.depth--
+		w.indent--
+		w.line("}")
+		return err
+	}
+	return fmt.Errorf("unsupported type %s", g.expr(typ))
+}
+
+// encodeString emits an encode of the string expression value described by t.
+func (g *generator) encodeString(w *writer, value string, t tag.Tag, owner string) error {
+	err := t.CheckString()
+	if err != nil {
+		return err
+	}
+	switch {
+	case t.Zero:
+		w.line("enc.StringZero(%s)", value)
+	case t.Prefix == "u8":
+		w.line("enc.StringLenPrefixUint8(%s)", value)
+	case t.Prefix == "u16":
+		w.line("enc.StringLenPrefixUint16(%s)", value)
+	case t.Prefix == "u32":
+		w.line("enc.StringLenPrefixUint32(%s)", value)
+	case t.Prefix == "uvarint":
+		w.line("enc.Uvarint(uint64(len(%s)))", value)
+		w.line("enc.String(%s)", value)
+	default:
+		count, err := countExpr(t, owner)
+		if err != nil {
+			return err
+		}
+		w.line("enc.StringFixed(%s, %s)", value, count)
+	}
+	return nil
+}
+
+// encodeSlice emits an encode of the counted slice target through the PutSlice function of its count,
+// which labels it and fails like Struct does on a count that overflows its prefix or does not match its len option.
+func (g *generator) encodeSlice(w *writer, target string, typ *ast.ArrayType, t tag.Tag, owner string) error {
+	args := ""
+	put := ""
+	switch t.Prefix {
+	case "u8":
+		put = "PutSliceU8"
+	case "u16":
+		put = "PutSliceU16"
+	case "u32":
+		put = "PutSliceU32"
+	case "uvarint":
+		put = "PutSliceUvarint"
+	default:
+		count, err := countExpr(t, owner)
+		if err != nil {
+			return err
+		}
+		put, args = "PutSliceN", ", "+count
+	}
+	label := target[strings.LastIndex(target, ".")+1:]
+	if isByteElem(typ.Elt, t) {
+		w.line("encdec.%s(enc.Field(%q), %s%s, (*encdec.Encoder).Uint8)", put, label, target, args)
+		return nil
+	}
+	item := itemName(target)
+	w.line("encdec.%s(enc.Field(%q), %s%s, func(enc *encdec.Encoder, %s %s) {", put, label, target, args, item, g.expr(typ.Elt))
+	w.indent++
+	w.depth++
+	err := g.encodeValue(w, item, typ.Elt, tag.Tag{Kind: t.Kind, Zero: t.Zero}, owner)
+	w.depth--
+	w.indent--
+	w.line("})")
+	return err
+}
+
+// expr renders an AST expression as source.
+func (g *generator) expr(e ast.Expr) string {
+	buf := bytes.Buffer{}
+	err := format.Node(&buf, g.fset, e)
+	if err != nil {
+		return fmt.Sprintf("%T", e)
+	}
+	return buf.String()
+}
+
+// identKind returns the value kind of a field of the named type, from its tag or the Go type.
+func identKind(typeName string, t tag.Tag) (string, error) {
+	if t.Kind != "" {
+		if t.Kind == "bytes" {
+			return "", fmt.Errorf("bytes kind on non-slice type %s", typeName)
+		}
+		return t.Kind, nil
+	}
+	kind, ok := tag.Infer(typeName)
+	if !ok {
+		return "", fmt.Errorf("type %s needs a kind option, e.g. `encdec:\"u32\"`", typeName)
+	}
+	return kind, nil
+}
+
+// countExpr returns the expression for the count of a len=N or len=Field option.
+func countExpr(t tag.Tag, owner string) (string, error) {
+	if t.Len == "" {
+		return "", fmt.Errorf("missing len, prefix or zero option")
+	}
+	if n, ok := t.Count(); ok {
+		return strconv.Itoa(n), nil
+	}
+	name, _ := t.LenField()
+	return "int(" + owner + "." + name + ")", nil
+}
+
+// convert wraps expr, of Go type from, in a conversion to Go type to when they differ.
+func convert(to string, from string, expr string) string {
+	if to == from {
+		return expr
+	}
+	return to + "(" + expr + ")"
+}
+
+// isByteElem reports whether a slice or array of elem is read as raw bytes.
+func isByteElem(elem ast.Expr, t tag.Tag) bool {
+	ident, ok := elem.(*ast.Ident)
+	if !ok || (ident.Name != "byte" && ident.Name != "uint8") {
+		return false
+	}
+	return t.Kind == "" || t.Kind == "bytes" || t.Kind == "u8"
+}
+
+// itemName returns the loop variable name for elements of target, e.g. someSubStructsItem for def.someSubStructs.
+func itemName(target string) string {
+	return varName(target) + "Item"
+}
+
+// lenName returns the variable name for the count prefix of target, e.g. someSubStructsLen for def.someSubStructs.
+func lenName(target string) string {
+	return varName(target) + "Len"
+}
+
+// varName builds a camel case variable name from the fields of a selector expression, dropping the receiver.
+func varName(target string) string {
+	parts := strings.Split(strings.TrimPrefix(target, "def."), ".")
+	sb := strings.Builder{}
+	for i, part := range parts {
+		part = strings.NewReplacer("[", "", "]", "").Replace(part)
+		if i == 0 {
+			r := []rune(part)
+			r[0] = unicode.ToLower(r[0])
+			sb.WriteString(string(r))
+			continue
+		}
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		sb.WriteString(string(r))
+	}
+	return sb.String()
+}
+
+// orderExpr returns the encoding/binary byte order of an le or be option.
+func orderExpr(order string) string {
+	if order == "be" {
+		return "binary.BigEndian"
+	}
+	return "binary.LittleEndian"
+}
+
+// markedTypes returns the struct types whose doc comment contains an encdec:generate line, sorted by name.
+func markedTypes(files []*ast.File) []string {
+	types := []string{}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if _, ok := ts.Type.(*ast.StructType); !ok {
+					continue
+				}
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				if doc != nil && strings.Contains(doc.Text(), "encdec:generate") {
+					types = append(types, ts.Name.Name)
+				}
+			}
+		}
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const genSource = `package sample
+
+// Header is a sample header.
+//
+// encdec:generate
+type Header struct {
+	Magic   [4]byte
+	Version uint16 ` + "`encdec:\"be\"`" + `
+	Count   uint32
+	Name    string ` + "`encdec:\"prefix=u8\"`" + `
+	Kind    Kind ` + "`encdec:\"u8\"`" + `
+	Entries []Entry ` + "`encdec:\"len=Count\"`" + `
+	Words   []uint16 ` + "`encdec:\"prefix=uvarint\"`" + `
+	Ignored int ` + "`encdec:\"-\"`" + `
+	hidden  int
+}
+
+type Kind uint8
+
+type Entry struct {
+	ID   uint32
+	Pos  Vec
+	Data []byte ` + "`encdec:\"prefix=u16\"`" + `
+}
+
+type Vec struct {
+	X, Y float32
+}
+
+type Outer struct {
+	In   Inner ` + "`encdec:\"le\"`" + `
+	Tail uint16
+}
+
+type Inner struct {
+	A uint16 ` + "`encdec:\"be\"`" + `
+	B uint16
+}
+
+type Broken struct {
+	Value complex64
+}
+
+type Labels struct {
+	List []string ` + "`encdec:\"zero,prefix=u8\"`" + `
+}
+
+type BadLabel struct {
+	Name string ` + "`encdec:\"zero,len=4\"`" + `
+}
+`
+
+const genWant = `// Code generated by encdecgen. DO NOT EDIT.
+
+package sample
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/xackery/encdec"
+)
+
+// Decode decodes Header from dec.
+func (def *Header) Decode(dec *encdec.Decoder) error {
+	order := dec.Order()
+	copy(def.Magic[:], dec.Bytes(len(def.Magic)))
+	dec.SetOrder(binary.BigEndian)
+	def.Version = dec.Uint16()
+	dec.SetOrder(order)
+	def.Count = dec.Uint32()
+	def.Name = dec.StringLenPrefixUint8()
+	def.Kind = Kind(dec.Uint8())
+	def.Entries = []Entry{}
+	for i := 0; i < int(def.Count) && dec.Error() == nil; i++ {
+		entriesItem := Entry{}
+		entriesItem.ID = dec.Uint32()
+		entriesItem.Pos.X = dec.Float32()
+		entriesItem.Pos.Y = dec.Float32()
+		entriesItemDataLen := dec.Uint16()
+		entriesItem.Data = dec.Bytes(int(entriesItemDataLen))
+		def.Entries = append(def.Entries, entriesItem)
+	}
+	wordsLen := dec.Uvarint()
+	def.Words = []uint16{}
+	for i := 0; i < int(wordsLen) && dec.Error() == nil; i++ {
+		wordsItem := dec.Uint16()
+		def.Words = append(def.Words, wordsItem)
+	}
+
+	if dec.Error() != nil {
+		return fmt.Errorf("decode Header: %w", dec.Error())
+	}
+	return nil
+}
+
+// Encode encodes Header to enc.
+func (def *Header) Encode(enc *encdec.Encoder) error {
+	order := enc.Order()
+	enc.Bytes(def.Magic[:])
+	enc.SetOrder(binary.BigEndian)
+	enc.Uint16(def.Version)
+	enc.SetOrder(order)
+	enc.Uint32(def.Count)
+	enc.StringLenPrefixUint8(def.Name)
+	enc.Uint8(uint8(def.Kind))
+	encdec.PutSliceN(enc.Field("Entries"), def.Entries, int(def.Count), func(enc *encdec.Encoder, entriesItem Entry) {
+		enc.Uint32(entriesItem.ID)
+		enc.Float32(entriesItem.Pos.X)
+		enc.Float32(entriesItem.Pos.Y)
+		encdec.PutSliceU16(enc.Field("Data"), entriesItem.Data, (*encdec.Encoder).Uint8)
+	})
+	encdec.PutSliceUvarint(enc.Field("Words"), def.Words, func(enc *encdec.Encoder, wordsItem uint16) {
+		enc.Uint16(wordsItem)
+	})
+
+	if enc.Error() != nil {
+		return fmt.Errorf("encode Header: %w", enc.Error())
+	}
+	return nil
+}
+`
+
+func parseSample(t *testing.T) (*generator, []*ast.File) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "sample.go", genSource, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	files := []*ast.File{f}
+	return newGenerator(fset, "sample", files), files
+}
+
+func TestGenerate(t *testing.T) {
+	g, files := parseSample(t)
+	types := markedTypes(files)
+	if len(types) != 1 || types[0] != "Header" {
+		t.Fatalf("markedTypes: got %v, want [Header]", types)
+	}
+	src, err := g.generate(types)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if string(src) != genWant {
+		t.Fatalf("generate mismatch\ngot:\n%s\nwant:\n%s", src, genWant)
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	g, _ := parseSample(t)
+	_, err := g.generate([]string{"Broken"})
+	if err == nil || !strings.Contains(err.Error(), "field Value") {
+		t.Fatalf("Broken: got %v, want field Value error", err)
+	}
+	_, err = g.generate([]string{"BadLabel"})
+	if err == nil || !strings.Contains(err.Error(), "field Name: zero cannot be combined") {
+		t.Fatalf("BadLabel: got %v, want field Name zero error", err)
+	}
+	_, err = g.generate([]string{"Missing"})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Missing: got %v, want not found error", err)
+	}
+}
+
+func TestGenerateNestedOrder(t *testing.T) {
+	g, _ := parseSample(t)
+	src, err := g.generate([]string{"Outer"})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	want := `	dec.SetOrder(binary.LittleEndian)
+	dec.SetOrder(binary.BigEndian)
+	def.In.A = dec.Uint16()
+	dec.SetOrder(binary.LittleEndian)
+	def.In.B = dec.Uint16()
+	dec.SetOrder(order)
+	def.Tail = dec.Uint16()
+`
+	if !strings.Contains(string(src), want) {
+		t.Fatalf("nested order not restored to the enclosing order, got:\n%s", src)
+	}
+}
+
+const genMain = `package sample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/xackery/encdec"
+)
+
+func TestRoundTrip(t *testing.T) {
+	want := Header{Count: 1, Name: "hdr", Entries: []Entry{{ID: 7, Data: []byte{1, 2}}}, Words: []uint16{3}}
+	enc := encdec.NewBytesEncoder(binary.LittleEndian)
+	if err := want.Encode(enc); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	got := Header{Entries: []Entry{{ID: 1}, {ID: 2}}}
+	if err := got.Decode(encdec.NewBytesDecoder(enc.Encoded(), binary.LittleEndian)); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(got.Entries) != 1 || got.Entries[0].ID != 7 || !bytes.Equal(got.Entries[0].Data, []byte{1, 2}) {
+		t.Fatalf("Decode into a reused value got %+v", got.Entries)
+	}
+
+	want.Count = 2
+	err := want.Encode(encdec.NewBytesEncoder(binary.LittleEndian))
+	var encErr *encdec.EncodeError
+	if !errors.As(err, &encErr) || encErr.Field != "Entries" {
+		t.Fatalf("Encode with Count not matching Entries = %v, want failure on Entries", err)
+	}
+
+	want.Count = 1
+	want.Entries[0].Data = make([]byte, 0x10000)
+	err = want.Encode(encdec.NewBytesEncoder(binary.LittleEndian))
+	if !errors.As(err, &encErr) || encErr.Field != "Entries[0].Data" {
+		t.Fatalf("Encode with Data overflowing its prefix = %v, want failure on Entries[0].Data", err)
+	}
+}
+`
+
+// TestGeneratedCode builds the generated methods against this module and runs them.
+func TestGeneratedCode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a module with the go command")
+	}
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatalf("module root: %v", err)
+	}
+	g, _ := parseSample(t)
+	src, err := g.generate([]string{"Header"})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                "module sample\n\ngo 1.20\n\nrequire github.com/xackery/encdec v0.0.0\n\nreplace github.com/xackery/encdec => " + root + "\n",
+		"sample.go":             strings.Replace(genSource, "type Broken struct {\n\tValue complex64\n}\n", "", 1),
+		"header_encdec.go":      string(src),
+		"header_encdec_test.go": genMain,
+	}
+	for name, content := range files {
+		err = os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	cmd := exec.Command("go", "test", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go test of generated code: %v\n%s", err, out)
+	}
+}
+
+func TestGenerateZeroStrings(t *testing.T) {
+	g, _ := parseSample(t)
+	src, err := g.generate([]string{"Labels"})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	for _, want := range []string{
+		"listItem := dec.StringZero()",
+		"enc.StringZero(listItem)",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Fatalf("generated Labels lacks %q:\n%s", want, src)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Command encdecgen generates Decode and Encode methods for structs annotated with encdec tags,
+// producing the same calls that would be written by hand without the cost of reflection.
+//
+// Usage, typically from a go:generate directive in the package holding the structs:
+//
+//	//go:generate go run github.com/xackery/encdec/cmd/encdecgen -type Header,Entry
+//
+// Without -type, every struct whose doc comment contains an encdec:generate line is used.
+// See the tag package documentation of encdec for the supported struct tag options.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "encdecgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	typeList := flag.String("type", "", "comma separated list of struct type names, default is structs marked encdec:generate")
+	output := flag.String("output", "", "output file name, default is <type>_encdec.go")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", dir, err)
+	}
+	if len(pkgs) != 1 {
+		return fmt.Errorf("expected 1 package in %s, found %d", dir, len(pkgs))
+	}
+
+	g := &generator{}
+	files := []*ast.File{}
+	for name, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			files = append(files, f)
+		}
+		g = newGenerator(fset, name, files)
+	}
+
+	types := []string{}
+	if *typeList != "" {
+		for _, name := range strings.Split(*typeList, ",") {
+			types = append(types, strings.TrimSpace(name))
+		}
+	} else {
+		types = markedTypes(files)
+	}
+	if len(types) == 0 {
+		return fmt.Errorf("no types given with -type or marked encdec:generate")
+	}
+
+	src, err := g.generate(types)
+	if err != nil {
+		return err
+	}
+
+	name := *output
+	if name == "" {
+		name = "encdec_gen.go"
+		if *typeList != "" {
+			name = strings.ToLower(types[0]) + "_encdec.go"
+		}
+		name = filepath.Join(dir, name)
+	}
+	err = os.WriteFile(name, src, 0644)
+	if err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package encdec
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrSizeMismatch is the cause recorded when a compressed block does not decompress to its stated size.
+var ErrSizeMismatch = errors.New("decompressed size mismatch")
+
+// Compression is the format of a compressed block.
+type Compression int
+
+const (
+	// Zlib is the zlib format of RFC 1950, deflate with a header and checksum.
+	Zlib Compression = iota
+	// Deflate is raw deflate as in RFC 1951.
+	Deflate
+	// Gzip is the gzip format of RFC 1952.
+	Gzip
+	// LZ4 is the LZ4 block format, without the frame of .lz4 files.
+	LZ4
+)
+
+// String returns the name of the compression.
+func (comp Compression) String() string {
+	switch comp {
+	case Zlib:
+		return "Zlib"
+	case Deflate:
+		return "Deflate"
+	case Gzip:
+		return "Gzip"
+	case LZ4:
+		return "LZ4"
+	}
+	return fmt.Sprintf("Compression(%d)", int(comp))
+}
+
+// Decompress reads compressedSize bytes compressed with comp, and returns a Decoder over the size bytes they decompress to.
+// A block that does not decompress to exactly size bytes records ErrSizeMismatch.
+// The returned Decoder is used like a section returned by Sub, with positions in errors and trace entries
+// relative to the decompressed data, and must be closed before the parent is used again.
+// In debug mode the compressed bytes are traced on d, and calls on the returned Decoder in a TraceEntry.Region of their own.
+func (d *Decoder) Decompress(comp Compression, compressedSize, size int64) *Decoder {
+	c := d.begin("Decompress")
+	return d.decompress(c, comp, compressedSize, size)
+}
+
+// CompressedBlock reads a block with a header of its uint32 compressed size and uint32 decompressed size,
+// as written by the Encoder's CompressedBlock, and returns a Decoder over the decompressed bytes, see Decompress.
+func (d *Decoder) CompressedBlock(comp Compression) *Decoder {
+	c := d.begin("CompressedBlock")
+	h := d.fixed(c, 8)
+	compressedSize, size := d.order.Uint32(h), d.order.Uint32(h[4:])
+	return d.decompress(c, comp, int64(compressedSize), int64(size))
+}
+
+// decompress reads and decompresses a block for the call c, returning a Decoder over the result.
+func (d *Decoder) decompress(c call, comp Compression, compressedSize, size int64) *Decoder {
+	d.bitLeft = 0
+	start := d.Pos()
+	var out []byte
+	switch {
+	case compressedSize < 0 || compressedSize > int64(maxInt):
+		d.fail(c, start, 0, fmt.Errorf("invalid compressed size %d", compressedSize))
+	case size < 0 || size > int64(maxInt):
+		d.fail(c, start, 0, fmt.Errorf("invalid decompressed size %d", size))
+	case d.limits.MaxAlloc > 0 && size > int64(d.limits.MaxAlloc):
+		d.fail(c, start, int(compressedSize), fmt.Errorf("%w: %d bytes exceeds max allocation of %d", ErrLimitExceeded, size, d.limits.MaxAlloc))
+	default:
+		lastError := d.lastError
+		packed := d.bytes(c, int(compressedSize))
+		if d.lastError != lastError {
+			break
+		}
+		var err error
+		out, err = decompress(comp, packed, int(size))
+		if err != nil {
+			d.fail(c, start, int(compressedSize), err)
+		}
+	}
+	region := d.trace.openRegion()
+	traceRead(d, c, region)
+	sub := d.child(c, &memory{b: out}, 0, int64(len(out)), d.Pos())
+	sub.trace.region = region
+	return sub
+}
+
+// decompress returns packed decompressed with comp, failing unless it is exactly size bytes.
+// Memory is allocated as data arrives, so a corrupt size cannot cause a huge allocation by itself.
+func decompress(comp Compression, packed []byte, size int) ([]byte, error) {
+	if comp == LZ4 {
+		// each byte of an LZ4 block produces at most 255 bytes
+		if size/255 > len(packed) {
+			return nil, fmt.Errorf("%w: %d bytes cannot come from %d", ErrSizeMismatch, size, len(packed))
+		}
+		out := make([]byte, size)
+		return out, lz4Decode(out, packed)
+	}
+	var r io.Reader
+	var err error
+	switch comp {
+	case Zlib:
+		r, err = zlib.NewReader(bytes.NewReader(packed))
+	case Deflate:
+		r = flate.NewReader(bytes.NewReader(packed))
+	case Gzip:
+		r, err = gzip.NewReader(bytes.NewReader(packed))
+	default:
+		err = fmt.Errorf("unsupported compression %v", comp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if size < sanityCheckSize {
+		buf.Grow(size)
+	} else {
+		buf.Grow(sanityCheckSize)
+	}
+	n, err := io.CopyN(&buf, r, int64(size))
+	if err == io.EOF {
+		return buf.Bytes(), fmt.Errorf("%w: got %d of %d bytes", ErrSizeMismatch, n, size)
+	}
+	if err != nil {
+		return buf.Bytes(), err
+	}
+	// reading to the end also verifies the checksums of zlib and gzip
+	var extra [1]byte
+	k, err := io.ReadFull(r, extra[:])
+	if k > 0 {
+		return buf.Bytes(), fmt.Errorf("%w: more than %d bytes", ErrSizeMismatch, size)
+	}
+	if err != io.EOF {
+		return buf.Bytes(), err
+	}
+	return buf.Bytes(), nil
+}
+
+// compressedBlock is the state of an Encoder returned by Compress or CompressedBlock.
+type compressedBlock struct {
+	parent    *Encoder
+	c         call
+	comp      Compression
+	hasHeader bool
+	isClosed  bool
+	region    int
+}
+
+// Compress returns an Encoder whose output is compressed with comp and written to e as one block by Close.
+// The returned Encoder has the settings and field labels of e, with positions relative to the uncompressed data,
+// and e must not be used until it is closed.
+// In debug mode the compressed bytes are traced on e, and calls on the returned Encoder in a TraceEntry.Region of their own.
+func (e *Encoder) Compress(comp Compression) *Encoder {
+	c := e.begin("Compress")
+	return e.compress(c, comp, false)
+}
+
+// CompressedBlock is like Compress, preceding the block with its uint32 compressed size and uint32 decompressed size,
+// as read by the Decoder's CompressedBlock.
+func (e *Encoder) CompressedBlock(comp Compression) *Encoder {
+	c := e.begin("CompressedBlock")
+	return e.compress(c, comp, true)
+}
+
+// compress returns an Encoder collecting a block to compress for the call c.
+func (e *Encoder) compress(c call, comp Compression, hasHeader bool) *Encoder {
+	sub := NewBytesEncoder(e.order)
+	sub.isDebugMode = e.isDebugMode
+	sub.bitOrder = e.bitOrder
+	sub.charset = e.charset
+	sub.block = &compressedBlock{parent: e, c: c, comp: comp, hasHeader: hasHeader, region: e.trace.openRegion()}
+	sub.trace.region, sub.trace.regions = sub.block.region, e.trace.regions
+	if c.label != "" {
+		sub.path.scopes = []string{c.label}
+	}
+	return sub
+}
+
+// Close compresses everything written to an Encoder returned by Compress or CompressedBlock and writes it to the parent.
+// It returns the first error of the Encoder, which is also recorded on the parent.
+// Nothing is written to the parent for a block that failed, so no header describes missing or corrupt data.
+// Close does nothing for other Encoders, or if called again.
+func (e *Encoder) Close() error {
+	b := e.block
+	if b == nil || b.isClosed {
+		return nil
+	}
+	b.isClosed = true
+	e.flushBits(b.c)
+	data := e.Encoded()
+	packed, err := compress(b.comp, data)
+	switch {
+	case err != nil:
+		e.fail(b.c, 0, len(data), err)
+	case b.hasHeader && (int64(len(data)) > math.MaxUint32 || int64(len(packed)) > math.MaxUint32):
+		e.fail(b.c, 0, len(data), fmt.Errorf("block of %d bytes overflows uint32 size", len(data)))
+	}
+	p := b.parent
+	p.trace.regions = e.trace.regions
+	if e.firstError != nil {
+		p.lastError = e.firstError
+		if p.firstError == nil {
+			p.firstError = p.lastError
+		}
+		return e.firstError
+	}
+	if b.hasHeader {
+		h := p.fixed(b.c, 8)
+		p.order.PutUint32(h, uint32(len(packed)))
+		p.order.PutUint32(h[4:], uint32(len(data)))
+		p.write(b.c, h)
+	}
+	p.write(b.c, packed)
+	traceWrite(p, b.c, b.region)
+	if p.isDebugMode {
+		p.trace.entries = append(p.trace.entries, e.trace.entries...)
+		p.trace.buf.Write(e.trace.buf.Bytes())
+	}
+	return nil
+}
+
+// compress returns data compressed with comp.
+func compress(comp Compression, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch comp {
+	case Zlib:
+		w = zlib.NewWriter(&buf)
+	case Deflate:
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case Gzip:
+		w = gzip.NewWriter(&buf)
+	case LZ4:
+		return lz4Encode(data), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %v", comp)
+	}
+	_, err := w.Write(data)
+	if err == nil {
+		err = w.Close()
+	}
+	return buf.Bytes(), err
+}
@@ -0,0 +1,181 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCompressedBlock(t *testing.T) {
+	for _, comp := range []Compression{Zlib, Deflate, Gzip, LZ4} {
+		t.Run(comp.String(), func(t *testing.T) {
+			enc := NewBytesEncoder(binary.LittleEndian)
+			enc.Uint16(0xbeef)
+			block := enc.Field("mesh").CompressedBlock(comp)
+			block.Uint32(3)
+			block.Float32s([]float32{1, 2, 3})
+			block.StringZero(strings.Repeat("vertex", 50))
+			if err := block.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			enc.Uint8(0x7f)
+			data := enc.Encoded()
+			if enc.Error() != nil || len(data) > 100 {
+				t.Fatalf("encoded %d bytes, %v", len(data), enc.Error())
+			}
+			if size := binary.LittleEndian.Uint32(data[6:]); size != 4+12+301 {
+				t.Fatalf("decompressed size header = %d", size)
+			}
+
+			dec := NewBytesDecoder(data, binary.LittleEndian)
+			dec.Uint16()
+			mesh := dec.Field("mesh").CompressedBlock(comp)
+			n := mesh.Uint32()
+			v := mesh.Float32s(int(n))
+			s := mesh.StringZero()
+			if err := mesh.Close(); err != nil || v[2] != 3 || len(s) != 300 {
+				t.Fatalf("block got %v, %d byte string, %v", v, len(s), err)
+			}
+			if tail := dec.Uint8(); tail != 0x7f || dec.Error() != nil {
+				t.Fatalf("tail = %#x, %v", tail, dec.Error())
+			}
+		})
+	}
+}
+
+func TestCompressedBlockTrace(t *testing.T) {
+	enc := NewBytesEncoder(binary.LittleEndian)
+	enc.SetDebugMode(true)
+	enc.Field("magic").Uint32(0x21434241)
+	block := enc.Field("mesh").CompressedBlock(LZ4)
+	block.Field("a").Uint16(7)
+	block.Close()
+	data := enc.Encoded()
+	entries := enc.Trace()
+	if len(entries) != 3 || entries[1].Op != "CompressedBlock" || entries[1].Offset != 4 || entries[1].Len != len(data)-4 || entries[1].Value != 1 {
+		t.Fatalf("block traced as %+v", entries)
+	}
+	if a := entries[2]; a.Label != "mesh.a" || a.Region != 1 || a.Offset != 0 {
+		t.Fatalf("a traced as %+v", a)
+	}
+
+	dec := NewBytesDecoder(data, binary.LittleEndian)
+	dec.SetDebugMode(true)
+	dec.Field("magic").Uint32()
+	mesh := dec.Field("mesh").CompressedBlock(LZ4)
+	mesh.Field("a").Uint16()
+	mesh.Close()
+	want := `00000000 |41 42 43 21|03 00 00 00 02 00 00 00 20 07 00     |ABC!........ .. |  magic = 558056001, mesh = 1
+region 1
+00000000 |07 00                                            |..              |  mesh.a = 7
+`
+	if got := dec.Hexdump(false); got != want {
+		t.Fatalf("Hexdump() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCompressedBlockErrors(t *testing.T) {
+	enc := NewBytesEncoder(binary.LittleEndian)
+	block := enc.CompressedBlock(Zlib)
+	block.Bytes(make([]byte, 100))
+	block.Close()
+	data := enc.Encoded()
+
+	// the header claims one more byte than the block holds
+	binary.LittleEndian.PutUint32(data[4:], 101)
+	dec := NewBytesDecoder(data, binary.LittleEndian)
+	dec.Field("block").CompressedBlock(Zlib).Close()
+	var decErr *DecodeError
+	if !errors.Is(dec.Error(), ErrSizeMismatch) || !errors.As(dec.Error(), &decErr) || decErr.Field != "block" {
+		t.Fatalf("oversized header: %v", dec.Error())
+	}
+
+	// a corrupt checksum is found by reading to the end
+	binary.LittleEndian.PutUint32(data[4:], 100)
+	data[len(data)-1] ^= 0xff
+	dec = NewBytesDecoder(data, binary.LittleEndian)
+	dec.CompressedBlock(Zlib).Close()
+	if dec.Error() == nil {
+		t.Fatalf("corrupt checksum was not reported")
+	}
+
+	dec = NewBytesDecoder(data, binary.LittleEndian)
+	dec.SetLimits(Limits{MaxAlloc: 50})
+	dec.CompressedBlock(Zlib).Close()
+	if !errors.Is(dec.Error(), ErrLimitExceeded) {
+		t.Fatalf("MaxAlloc: %v", dec.Error())
+	}
+
+	// the block must be read to the end
+	data[len(data)-1] ^= 0xff
+	dec = NewBytesDecoder(data, binary.LittleEndian)
+	sub := dec.CompressedBlock(Zlib)
+	sub.Uint8()
+	if err := sub.Close(); !errors.Is(err, ErrSectionUnderrun) {
+		t.Fatalf("Close() = %v, want underrun", err)
+	}
+}
+
+// snowmanBlock writes a compressed block whose only field cannot be encoded.
+type snowmanBlock struct{}
+
+func (snowmanBlock) EncodeTo(enc *Encoder) {
+	block := enc.Field("mesh").CompressedBlock(Zlib)
+	block.WithCharset(Latin1).Field("x").StringZero("☃")
+	block.Close()
+}
+
+func TestCompressedBlockEncodeErrors(t *testing.T) {
+	// a failed block is labeled under the parent's scopes, and nothing of it is written
+	enc := NewBytesEncoder(binary.LittleEndian)
+	enc.Uint8(1)
+	enc.Field("outer").Value(snowmanBlock{})
+	var encErr *EncodeError
+	if !errors.Is(enc.Error(), ErrUnmappable) || !errors.As(enc.Error(), &encErr) || encErr.Field != "outer.mesh.x" {
+		t.Fatalf("Error() = %v, want unmappable outer.mesh.x", enc.Error())
+	}
+	if got := enc.Encoded(); !bytes.Equal(got, []byte{1}) {
+		t.Fatalf("failed block wrote % x", got)
+	}
+
+	// so does a block whose compression fails
+	enc = NewBytesEncoder(binary.LittleEndian)
+	block := enc.Field("mesh").CompressedBlock(Compression(99))
+	block.Uint8(1)
+	if err := block.Close(); err == nil || !errors.As(enc.Error(), &encErr) || encErr.Field != "mesh" || enc.Len() != 0 {
+		t.Fatalf("Close() = %v with %d bytes written, want unsupported compression on mesh", err, enc.Len())
+	}
+}
+
+func TestLZ4(t *testing.T) {
+	// "abc", a 9 byte match at offset 3, then the final literals
+	block := []byte{0x35, 'a', 'b', 'c', 0x03, 0x00, 0x50, 'x', 'y', 'z', '1', '2'}
+	out := make([]byte, 17)
+	if err := lz4Decode(out, block); err != nil || string(out) != "abcabcabcabcxyz12" {
+		t.Fatalf("lz4Decode = %q, %v", out, err)
+	}
+	if err := lz4Decode(make([]byte, 18), block); err == nil {
+		t.Fatalf("lz4Decode into a larger buffer succeeded")
+	}
+	if err := lz4Decode(make([]byte, 17), block[:5]); err == nil {
+		t.Fatalf("lz4Decode of a truncated block succeeded")
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	noise := make([]byte, 100000)
+	rnd.Read(noise)
+	inputs := [][]byte{nil, []byte("a"), []byte("abcabcabcabcabc"), make([]byte, 1<<20), noise, bytes.Repeat([]byte("0123456789abcdefghij"), 5000)}
+	for _, in := range inputs {
+		packed := lz4Encode(in)
+		out := make([]byte, len(in))
+		if err := lz4Decode(out, packed); err != nil || !bytes.Equal(out, in) {
+			t.Fatalf("round trip of %d bytes through %d failed: %v", len(in), len(packed), err)
+		}
+		if len(in) > 1000 && in[0] == 0 && len(packed) > len(in)/200 {
+			t.Fatalf("%d zero bytes packed into %d", len(in), len(packed))
+		}
+	}
+}
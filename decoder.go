@@ -1,9 +1,7 @@
 package encdec
 
 import (
-	"bytes"
 	"encoding/binary"
-	"fmt"
 	"io"
 	"math"
 )
@@ -15,7 +13,25 @@ type Decoder struct {
 	firstError  error
 	lastError   error
 	isDebugMode bool
-	debugBuf    bytes.Buffer
+	trace       tracer
+	path        fieldPath
+	bitOrder    BitOrder
+	bitCur      byte
+	bitLeft     int
+	base        int64
+	parent      *Decoder
+	subCall     call
+	subEnd      int64
+	subSize     int64
+	closed      bool
+	limits      Limits
+	total       int64
+	scratch     [8]byte
+	isZeroCopy  bool
+	closer      func() error
+	charset     Charset
+	nextText    text
+	transforms  []readLayer
 }
 
 // NewDecoder returns new Decoder.
@@ -26,24 +42,29 @@ func NewDecoder(r io.ReadSeeker, order binary.ByteOrder) *Decoder {
 	}
 }
 
-// SetDebugMode enables every decode call to write to a stored buffer in the decoder to review later
+// SetDebugMode enables every decode call to be recorded as a TraceEntry in the decoder to review later
 func (d *Decoder) SetDebugMode(value bool) {
 	d.isDebugMode = value
 }
 
-// DebugBuf returns the debug buffer
+// Trace returns an entry for every decode call made while debug mode was enabled
+func (d *Decoder) Trace() []TraceEntry {
+	return d.trace.entries
+}
+
+// DebugBuf returns the raw bytes of every traced decode call
 func (d *Decoder) DebugBuf() []byte {
-	return d.debugBuf.Bytes()
+	return d.trace.buf.Bytes()
 }
 
 // DebugString returns the debug buffer as a string
 func (d *Decoder) DebugString() string {
-	return string(d.debugBuf.Bytes())
+	return d.trace.buf.String()
 }
 
-// DebugClear clears the debug buffer
+// DebugClear clears the trace and debug buffer
 func (d *Decoder) DebugClear() {
-	d.debugBuf.Reset()
+	d.trace.reset()
 }
 
 // IsDebugMode returns if debug mode is enabled
@@ -56,6 +77,11 @@ func (d *Decoder) SetOrder(order binary.ByteOrder) {
 	d.order = order
 }
 
+// Order returns byte order.
+func (d *Decoder) Order() binary.ByteOrder {
+	return d.order
+}
+
 // LastError returns last error that occurred during read.
 func (d *Decoder) LastError() error {
 	return d.lastError
@@ -66,6 +92,125 @@ func (d *Decoder) Error() error {
 	return d.firstError
 }
 
+// Field labels the next read with name, nested under any open scopes.
+// It returns the decoder so the read can be chained, e.g. dec.Field("version").Uint16().
+func (d *Decoder) Field(name string) *Decoder {
+	d.path.next = name
+	return d
+}
+
+// Scope opens a nested scope that prefixes every label until EndScope is called.
+func (d *Decoder) Scope(name string) {
+	d.path.push(name)
+}
+
+// Index opens a nested scope for element i of an array, rendered as "[i]".
+func (d *Decoder) Index(i int) {
+	d.path.push(indexLabel(i))
+}
+
+// EndScope closes the innermost scope opened by Scope or Index.
+func (d *Decoder) EndScope() {
+	d.path.pop()
+}
+
+// Label returns the label the next read will be recorded with.
+func (d *Decoder) Label() string {
+	return d.path.join(d.path.next)
+}
+
+// begin starts a public read call, consuming the pending field label.
+func (d *Decoder) begin(op string) call {
+	c := call{op: op, label: d.path.take()}
+	d.nextText = text{}
+	if d.isDebugMode {
+		c.pos = d.absPos(d.Pos())
+		c.region = d.trace.region
+		d.trace.pending.Reset()
+	}
+	return c
+}
+
+// traceRead finishes a public read call started by begin, tracing value in debug mode.
+// It is generic so value is only converted to an interface while tracing, keeping untraced reads free of allocations.
+func traceRead[T any](d *Decoder, c call, value T) {
+	if !d.isDebugMode {
+		return
+	}
+	d.trace.record(c, value, d.order)
+}
+
+// traceReadSlice is traceRead for a slice that aliases the caller's memory or the stream's,
+// tracing a copy so later changes to it do not alter the trace.
+func traceReadSlice[T any](d *Decoder, c call, s []T) {
+	if !d.isDebugMode {
+		return
+	}
+	d.trace.record(c, append([]T(nil), s...), d.order)
+}
+
+// reader returns the stream to read from, teeing into the trace in debug mode.
+func (d *Decoder) reader() io.Reader {
+	var r io.Reader = budgetReader{d}
+	if !d.isDebugMode {
+		return r
+	}
+	return io.TeeReader(r, &d.trace.pending)
+}
+
+// fail records err as a DecodeError for a read of size bytes started at pos.
+func (d *Decoder) fail(c call, pos int64, size int, err error) {
+	d.lastError = &DecodeError{
+		Offset: d.absPos(pos),
+		Op:     c.op,
+		Size:   size,
+		Field:  c.label,
+		Err:    err,
+	}
+	if d.firstError == nil {
+		d.firstError = d.lastError
+	}
+}
+
+// fixed reads size bytes, at most 8, into the scratch buffer, recording any failure.
+// On failure the returned bytes are zero, so the decoded value is too.
+// Like every byte aligned read, it discards the rest of a partially consumed byte.
+func (d *Decoder) fixed(c call, size int) []byte {
+	d.bitLeft = 0
+	b := d.scratch[:size]
+	n, err := io.ReadFull(d.reader(), b)
+	if err != nil {
+		pos := d.Pos()
+		if pos >= 0 {
+			pos -= int64(n)
+		}
+		d.fail(c, pos, size, err)
+		for i := range b {
+			b[i] = 0
+		}
+	}
+	return b
+}
+
+// bytes reads n bytes, recording any failure.
+// It returns nil without reading if n breaks the Decoder's Limits.
+func (d *Decoder) bytes(c call, n int) []byte {
+	d.bitLeft = 0
+	pos := d.Pos()
+	if !d.checkAlloc(c, pos, n) {
+		return nil
+	}
+	if d.isZeroCopy && canSlice(d.r) {
+		return d.sliceBytes(c, pos, n)
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(d.reader(), b)
+	if err != nil {
+		d.fail(c, pos, n, err)
+	}
+	return b
+}
+
 // Pos returns current position.
 func (d *Decoder) Pos() int64 {
 	pos, err := d.r.Seek(0, io.SeekCurrent)
@@ -75,346 +220,187 @@ func (d *Decoder) Pos() int64 {
 	return pos
 }
 
+// absPos converts a position of this Decoder to one in the outermost stream, for sections returned by Sub.
+// Inside a transform pushed by PushTransform, positions are already relative to the region.
+func (d *Decoder) absPos(pos int64) int64 {
+	if pos < 0 || len(d.transforms) > 0 {
+		return pos
+	}
+	return d.base + pos
+}
+
 // Bytes returns bytes.
 func (d *Decoder) Bytes(n int) []byte {
-	b := make([]byte, n)
-	_, err := io.ReadFull(d.r, b)
-	if err != nil {
-		pos, err := d.r.Seek(0, io.SeekCurrent)
-		if err != nil {
-			pos = -1
-		}
-		d.lastError = fmt.Errorf("pos %d: %w", pos, err)
-		if d.firstError != nil {
-			d.firstError = d.lastError
-		}
-	}
-	if d.isDebugMode {
-		d.debugBuf.Write(b)
-	}
+	c := d.begin("Bytes")
+	b := d.bytes(c, n)
+	traceReadSlice(d, c, b)
 	return b
 }
 
 // Byte returns byte.
 func (d *Decoder) Byte() byte {
-	if d.isDebugMode {
-		value := d.Bytes(1)[0]
-		d.debugBuf.Write([]byte{value})
-		return value
-	}
-	return d.Bytes(1)[0]
+	c := d.begin("Byte")
+	value := d.fixed(c, 1)[0]
+	traceRead(d, c, value)
+	return value
 }
 
-// StringFixed returns fixed string.
+// StringFixed returns fixed string of n code units of the Charset, which are bytes unless it is UTF-16.
+// Padding is returned as part of the string, see StringPadded to remove it.
 func (d *Decoder) StringFixed(n int) string {
-	if d.isDebugMode {
-		value := string(d.Bytes(n))
-		d.debugBuf.Write([]byte(value))
-		return value
-	}
-	return string(d.Bytes(n))
+	t := d.takeText()
+	c := d.begin("StringFixed")
+	value := d.decodeString(c, t.charset, t.decipher(d.stringBytes(c, n*t.charset.UnitSize())))
+	traceRead(d, c, value)
+	return value
 }
 
 // StringLenPrefixUint32 returns string with length prefix assumed to be prior
 func (d *Decoder) StringLenPrefixUint32() string {
-	n := d.Uint32()
-	if d.isDebugMode {
-		value := d.StringFixed(int(n))
-		d.debugBuf.Write([]byte(value))
-		return value
-	}
-	return d.StringFixed(int(n))
+	t := d.takeText()
+	c := d.begin("StringLenPrefixUint32")
+	n := d.order.Uint32(d.fixed(c, 4))
+	value := d.decodeString(c, t.charset, t.decipher(d.stringBytes(c, int(n)*t.charset.UnitSize())))
+	traceRead(d, c, value)
+	return value
 }
 
 // StringLenPrefixUint16 returns string with length prefix assumed to be prior
 func (d *Decoder) StringLenPrefixUint16() string {
-	n := d.Uint16()
-	if d.isDebugMode {
-		value := d.StringFixed(int(n))
-		d.debugBuf.Write([]byte(value))
-		return value
-	}
-	return d.StringFixed(int(n))
+	t := d.takeText()
+	c := d.begin("StringLenPrefixUint16")
+	n := d.order.Uint16(d.fixed(c, 2))
+	value := d.decodeString(c, t.charset, t.decipher(d.stringBytes(c, int(n)*t.charset.UnitSize())))
+	traceRead(d, c, value)
+	return value
 }
 
 // StringLenPrefixUint8 returns string with length prefix assumed to be prior
 func (d *Decoder) StringLenPrefixUint8() string {
-	n := d.Uint8()
-	if d.isDebugMode {
-		value := d.StringFixed(int(n))
-		d.debugBuf.Write([]byte(value))
-		return value
-	}
-
-	return d.StringFixed(int(n))
+	t := d.takeText()
+	c := d.begin("StringLenPrefixUint8")
+	n := d.fixed(c, 1)[0]
+	value := d.decodeString(c, t.charset, t.decipher(d.stringBytes(c, int(n)*t.charset.UnitSize())))
+	traceRead(d, c, value)
+	return value
 }
 
 // StringZero reads the read stream until a zero terminator is found.
+// The terminator is one code unit of the Charset, so UTF-16 strings end with two zero bytes.
 func (d *Decoder) StringZero() string {
-	var s string
-	var buf [1]byte
-	var err error
+	t := d.takeText()
+	c := d.begin("StringZero")
+	var b []byte
+	buf := d.scratch[:t.charset.UnitSize()]
+	d.bitLeft = 0
+	pos := d.Pos()
+	r := d.reader()
 	for {
-		_, err = io.ReadFull(d.r, buf[:])
+		_, err := io.ReadFull(r, buf)
 		if err != nil {
-			pos, err := d.r.Seek(0, io.SeekCurrent)
-			if err != nil {
-				pos = -1
-			}
-			d.lastError = fmt.Errorf("pos %d: %w", pos, err)
-			if d.firstError != nil {
-				d.firstError = d.lastError
-			}
+			d.fail(c, pos, 0, err)
 			break
 		}
-		if buf[0] == 0 {
+		if t.cipher != nil {
+			t.cipher.Decode(buf, int64(len(b)))
+		}
+		if string(buf) == zeroUnits[:len(buf)] {
 			break
 		}
-		s += string(buf[:])
-	}
-	if d.isDebugMode {
-		d.debugBuf.Write([]byte(s))
+		if !d.checkString(c, pos, len(b)+len(buf)) {
+			break
+		}
+		b = append(b, buf...)
 	}
-
+	s := d.decodeString(c, t.charset, b)
+	traceRead(d, c, s)
 	return s
 }
 
 // Bool returns bool.
 func (d *Decoder) Bool() bool {
-	if d.isDebugMode {
-		value := d.Byte() != 0
-		d.debugBuf.Write([]byte{0})
-		return value
-	}
-
-	return d.Byte() != 0
+	c := d.begin("Bool")
+	value := d.fixed(c, 1)[0]
+	traceRead(d, c, value != 0)
+	return value != 0
 }
 
 // Uint8 returns uint8.
 func (d *Decoder) Uint8() uint8 {
-	var v uint8
-	err := binary.Read(d.r, d.order, &v)
-	if err != nil {
-		pos, err := d.r.Seek(0, io.SeekCurrent)
-		if err != nil {
-			pos = -1
-		}
-		d.lastError = fmt.Errorf("pos %d: %w", pos, err)
-		if d.firstError != nil {
-			d.firstError = d.lastError
-		}
-	}
-	if d.isDebugMode {
-		d.debugBuf.Write([]byte{v})
-	}
+	c := d.begin("Uint8")
+	v := d.fixed(c, 1)[0]
+	traceRead(d, c, v)
 	return v
 }
 
 // Uint16 returns uint16.
 func (d *Decoder) Uint16() uint16 {
-	var v uint16
-	err := binary.Read(d.r, d.order, &v)
-	if err != nil {
-		pos, err := d.r.Seek(0, io.SeekCurrent)
-		if err != nil {
-			pos = -1
-		}
-		d.lastError = fmt.Errorf("pos %d: %w", pos, err)
-		if d.firstError != nil {
-			d.firstError = d.lastError
-		}
-	}
-
-	if d.isDebugMode {
-		d.debugBuf.Write([]byte{byte(v >> 8), byte(v)})
-	}
+	c := d.begin("Uint16")
+	v := d.order.Uint16(d.fixed(c, 2))
+	traceRead(d, c, v)
 	return v
 }
 
 // Uint32 returns uint32.
 func (d *Decoder) Uint32() uint32 {
-	var v uint32
-	err := binary.Read(d.r, d.order, &v)
-	if err != nil {
-		pos, err := d.r.Seek(0, io.SeekCurrent)
-		if err != nil {
-			pos = -1
-		}
-
-		d.lastError = fmt.Errorf("pos %d: %w", pos, err)
-		if d.firstError != nil {
-			d.firstError = d.lastError
-		}
-	}
-
-	if d.isDebugMode {
-		d.debugBuf.Write([]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
-	}
-
+	c := d.begin("Uint32")
+	v := d.order.Uint32(d.fixed(c, 4))
+	traceRead(d, c, v)
 	return v
 }
 
 // Uint64 returns uint64.
 func (d *Decoder) Uint64() uint64 {
-	var v uint64
-	err := binary.Read(d.r, d.order, &v)
-	if err != nil {
-		pos, err := d.r.Seek(0, io.SeekCurrent)
-		if err != nil {
-			pos = -1
-		}
-
-		d.lastError = fmt.Errorf("pos %d: %w", pos, err)
-		if d.firstError != nil {
-			d.firstError = d.lastError
-		}
-	}
-
-	if d.isDebugMode {
-		d.debugBuf.Write([]byte{byte(v >> 56), byte(v >> 48), byte(v >> 40), byte(v >> 32),
-			byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
-	}
+	c := d.begin("Uint64")
+	v := d.order.Uint64(d.fixed(c, 8))
+	traceRead(d, c, v)
 	return v
 }
 
 // Int8 returns int8.
 func (d *Decoder) Int8() int8 {
-	var v int8
-	err := binary.Read(d.r, d.order, &v)
-	if err != nil {
-		pos, err := d.r.Seek(0, io.SeekCurrent)
-		if err != nil {
-			pos = -1
-		}
-		d.lastError = fmt.Errorf("pos %d: %w", pos, err)
-		if d.firstError != nil {
-			d.firstError = d.lastError
-		}
-	}
-
-	if d.isDebugMode {
-		d.debugBuf.Write([]byte{byte(v)})
-	}
-
+	c := d.begin("Int8")
+	v := int8(d.fixed(c, 1)[0])
+	traceRead(d, c, v)
 	return v
 }
 
 // Int16 returns int16.
 func (d *Decoder) Int16() int16 {
-	var v int16
-	err := binary.Read(d.r, d.order, &v)
-	if err != nil {
-		pos, err := d.r.Seek(0, io.SeekCurrent)
-		if err != nil {
-			pos = -1
-		}
-		d.lastError = fmt.Errorf("pos %d: %w", pos, err)
-		if d.firstError != nil {
-			d.firstError = d.lastError
-		}
-	}
-
-	if d.isDebugMode {
-		d.debugBuf.Write([]byte{byte(v >> 8), byte(v)})
-	}
-
+	c := d.begin("Int16")
+	v := int16(d.order.Uint16(d.fixed(c, 2)))
+	traceRead(d, c, v)
 	return v
 }
 
 // Int32 returns int32.
 func (d *Decoder) Int32() int32 {
-	var v int32
-	err := binary.Read(d.r, d.order, &v)
-	if err != nil {
-		pos, err := d.r.Seek(0, io.SeekCurrent)
-		if err != nil {
-			pos = -1
-		}
-
-		d.lastError = fmt.Errorf("pos %d: %w", pos, err)
-		if d.firstError != nil {
-			d.firstError = d.lastError
-		}
-	}
-
-	if d.isDebugMode {
-		d.debugBuf.Write([]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
-	}
-
+	c := d.begin("Int32")
+	v := int32(d.order.Uint32(d.fixed(c, 4)))
+	traceRead(d, c, v)
 	return v
 }
 
 // Int64 returns int64.
 func (d *Decoder) Int64() int64 {
-	var v int64
-	err := binary.Read(d.r, d.order, &v)
-	if err != nil {
-		pos, err := d.r.Seek(0, io.SeekCurrent)
-		if err != nil {
-			pos = -1
-		}
-
-		d.lastError = fmt.Errorf("pos %d: %w", pos, err)
-		if d.firstError != nil {
-			d.firstError = d.lastError
-		}
-	}
-
-	if d.isDebugMode {
-		d.debugBuf.Write([]byte{byte(v >> 56), byte(v >> 48), byte(v >> 40), byte(v >> 32),
-			byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
-	}
-
+	c := d.begin("Int64")
+	v := int64(d.order.Uint64(d.fixed(c, 8)))
+	traceRead(d, c, v)
 	return v
 }
 
 // Float32 returns float32.
 func (d *Decoder) Float32() float32 {
-	var v float32
-	err := binary.Read(d.r, d.order, &v)
-	if err != nil {
-		pos, err := d.r.Seek(0, io.SeekCurrent)
-		if err != nil {
-			pos = -1
-		}
-
-		d.lastError = fmt.Errorf("pos %d: %w", pos, err)
-		if d.firstError != nil {
-			d.firstError = d.lastError
-		}
-	}
-
-	if d.isDebugMode {
-		// Convert float32 to uint32 bits and write those bytes
-		bits := math.Float32bits(v)
-		d.debugBuf.Write([]byte{byte(bits >> 24), byte(bits >> 16), byte(bits >> 8), byte(bits)})
-	}
-
+	c := d.begin("Float32")
+	v := math.Float32frombits(d.order.Uint32(d.fixed(c, 4)))
+	traceRead(d, c, v)
 	return v
 }
 
 // Float64 returns float64.
 func (d *Decoder) Float64() float64 {
-	var v float64
-	err := binary.Read(d.r, d.order, &v)
-	if err != nil {
-		pos, err := d.r.Seek(0, io.SeekCurrent)
-		if err != nil {
-			pos = -1
-		}
-
-		d.lastError = fmt.Errorf("pos %d: %w", pos, err)
-		if d.firstError != nil {
-			d.firstError = d.lastError
-		}
-	}
-
-	if d.isDebugMode {
-		// Convert float64 to uint64 bits and write those bytes
-		bits := math.Float64bits(v)
-		d.debugBuf.Write([]byte{byte(bits >> 56), byte(bits >> 48), byte(bits >> 40), byte(bits >> 32),
-			byte(bits >> 24), byte(bits >> 16), byte(bits >> 8), byte(bits)})
-	}
-
+	c := d.begin("Float64")
+	v := math.Float64frombits(d.order.Uint64(d.fixed(c, 8)))
+	traceRead(d, c, v)
 	return v
 }
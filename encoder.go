@@ -1,8 +1,10 @@
 package encdec
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"math"
 )
@@ -13,37 +15,71 @@ type Encoder struct {
 	w           io.Writer
 	firstError  error
 	lastError   error
-	lastPos     int64
+	pos         int64
+	start       int64
+	canSeek     bool
+	bw          *bufio.Writer
 	isDebugMode bool
-	debugBuf    bytes.Buffer
+	trace       tracer
+	path        fieldPath
+	bitOrder    BitOrder
+	bitCur      byte
+	bitCount    int
+	hold        bytes.Buffer
+	holdPos     int64
+	holdCount   int // held data written out so far, telling placeholders set in earlier held data apart
+	unresolved  int
+	scratch     [binary.MaxVarintLen64]byte
+	charset     Charset
+	nextText    text
+	cipher      Cipher
+	cipherStart int64
+	cipherBuf   []byte
+	transforms  []writeLayer
+	block       *compressedBlock
 }
 
 // NewEncoder returns new Encoder.
+// Positions start at the current offset of w if it is an io.Seeker, otherwise at 0.
 func NewEncoder(w io.Writer, order binary.ByteOrder) *Encoder {
-	return &Encoder{
+	e := &Encoder{
 		order: order,
 		w:     w,
 	}
+	if seeker, ok := w.(io.WriteSeeker); ok {
+		pos, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			e.pos = pos
+			e.start = pos
+			e.canSeek = true
+		}
+	}
+	return e
 }
 
-// SetDebugMode enables every encode call to write to a stored buffer in the encoder to review later
+// SetDebugMode enables every encode call to be recorded as a TraceEntry in the encoder to review later
 func (e *Encoder) SetDebugMode(value bool) {
 	e.isDebugMode = value
 }
 
-// DebugBuf returns the debug buffer
+// Trace returns an entry for every encode call made while debug mode was enabled
+func (e *Encoder) Trace() []TraceEntry {
+	return e.trace.entries
+}
+
+// DebugBuf returns the raw bytes of every traced encode call
 func (e *Encoder) DebugBuf() []byte {
-	return e.debugBuf.Bytes()
+	return e.trace.buf.Bytes()
 }
 
 // DebugString returns the debug buffer as a string
 func (e *Encoder) DebugString() string {
-	return string(e.debugBuf.Bytes())
+	return e.trace.buf.String()
 }
 
-// DebugClear clears the debug buffer
+// DebugClear clears the trace and debug buffer
 func (e *Encoder) DebugClear() {
-	e.debugBuf.Reset()
+	e.trace.reset()
 }
 
 // IsDebugMode returns if debug mode is enabled
@@ -56,262 +92,340 @@ func (e *Encoder) SetOrder(order binary.ByteOrder) {
 	e.order = order
 }
 
-// Pos returns current position (attemps to track if not a seeker writer).
+// Order returns byte order.
+func (e *Encoder) Order() binary.ByteOrder {
+	return e.order
+}
+
+// Pos returns current position, counting data still buffered or held for placeholders.
 func (e *Encoder) Pos() int64 {
-	seeker, ok := e.w.(io.Seeker)
-	if !ok {
-		return e.lastPos
+	return e.pos
+}
+
+// Field labels the next write with name, nested under any open scopes.
+// It returns the encoder so the write can be chained, e.g. enc.Field("version").Uint16(v).
+func (e *Encoder) Field(name string) *Encoder {
+	e.path.next = name
+	return e
+}
+
+// Scope opens a nested scope that prefixes every label until EndScope is called.
+func (e *Encoder) Scope(name string) {
+	e.path.push(name)
+}
+
+// Index opens a nested scope for element i of an array, rendered as "[i]".
+func (e *Encoder) Index(i int) {
+	e.path.push(indexLabel(i))
+}
+
+// EndScope closes the innermost scope opened by Scope or Index.
+func (e *Encoder) EndScope() {
+	e.path.pop()
+}
+
+// Label returns the label the next write will be recorded with.
+func (e *Encoder) Label() string {
+	return e.path.join(e.path.next)
+}
+
+// begin starts a public write call, consuming the pending field label.
+func (e *Encoder) begin(op string) call {
+	c := call{op: op, label: e.path.take()}
+	e.nextText = text{}
+	if e.isDebugMode {
+		c.pos = e.Pos()
+		c.region = e.trace.region
+		e.trace.pending.Reset()
+	}
+	return c
+}
+
+// traceWrite finishes a public write call started by begin, tracing value in debug mode.
+// It is generic so value is only converted to an interface while tracing, keeping untraced writes free of allocations.
+func traceWrite[T any](e *Encoder, c call, value T) {
+	if !e.isDebugMode {
+		return
+	}
+	e.trace.record(c, value, e.order)
+}
+
+// traceWriteSlice is traceWrite for a slice owned by the caller, tracing a copy so later changes to it do not alter the trace.
+func traceWriteSlice[T any](e *Encoder, c call, s []T) {
+	if !e.isDebugMode {
+		return
+	}
+	e.trace.record(c, append([]T(nil), s...), e.order)
+}
+
+// writer returns the stream to write to, copying into the trace in debug mode.
+// While a placeholder is unset on a writer that cannot seek, writes are held in memory.
+// Inside a region set by SetCipher, writes are encoded, though the trace keeps the bytes as written.
+func (e *Encoder) writer() io.Writer {
+	w := e.sink()
+	if e.unresolved > 0 {
+		w = &e.hold
 	}
-	pos, err := seeker.Seek(0, io.SeekCurrent)
+	if e.cipher != nil {
+		w = cipherWriter{e, w}
+	}
+	if !e.isDebugMode {
+		return w
+	}
+	return io.MultiWriter(w, &e.trace.pending)
+}
+
+// fail records err as an EncodeError for a write of size bytes started at pos.
+func (e *Encoder) fail(c call, pos int64, size int, err error) {
+	e.lastError = &EncodeError{
+		Offset: pos,
+		Op:     c.op,
+		Size:   size,
+		Field:  c.label,
+		Err:    err,
+	}
+	if e.firstError == nil {
+		e.firstError = e.lastError
+	}
+}
+
+// fixed returns the scratch buffer to encode a value of size bytes into, for a following write.
+// Like every byte aligned write, it first writes out a partially written byte, which also uses the scratch buffer.
+func (e *Encoder) fixed(c call, size int) []byte {
+	e.flushBits(c)
+	return e.scratch[:size]
+}
+
+// write writes b, recording any failure.
+// Like every byte aligned write, it first writes out a partially written byte.
+func (e *Encoder) write(c call, b []byte) {
+	e.flushBits(c)
+	pos := e.Pos()
+	_, err := e.writer().Write(b)
 	if err != nil {
-		pos = -1
+		e.fail(c, pos, len(b), err)
 	}
-	return pos
+	e.pos += int64(len(b))
 }
 
 // Bytes writes bytes.
 func (e *Encoder) Bytes(b []byte) {
-	err := binary.Write(e.w, e.order, b)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		e.debugBuf.Write(b)
-	}
-	e.lastPos += int64(len(b))
+	c := e.begin("Bytes")
+	e.write(c, b)
+	traceWriteSlice(e, c, b)
 }
 
 // Byte writes byte.
 func (e *Encoder) Byte(b byte) {
-	err := binary.Write(e.w, e.order, b)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		e.debugBuf.WriteByte(b)
-	}
-	e.lastPos++
+	c := e.begin("Byte")
+	buf := e.fixed(c, 1)
+	buf[0] = b
+	e.write(c, buf)
+	traceWrite(e, c, b)
 }
 
 // String writes string.
 func (e *Encoder) String(s string) {
-	e.Bytes([]byte(s))
+	t := e.takeText()
+	c := e.begin("String")
+	e.writeString(c, t.encipher(e.encodeString(c, t.charset, s), 0))
+	traceWrite(e, c, s)
+}
+
+// writeString writes the raw bytes of s, recording any failure.
+func (e *Encoder) writeString(c call, s string) {
+	e.flushBits(c)
+	pos := e.Pos()
+	_, err := io.WriteString(e.writer(), s)
+	if err != nil {
+		e.fail(c, pos, len(s), err)
+	}
+	e.pos += int64(len(s))
 }
 
 // StringZero writes string with zero terminator.
+// The terminator is one code unit of the Charset, so UTF-16 strings end with two zero bytes.
 func (e *Encoder) StringZero(s string) {
-	e.Bytes([]byte(s))
-	e.Bytes([]byte{0})
+	t := e.takeText()
+	c := e.begin("StringZero")
+	stored := e.encodeString(c, t.charset, s)
+	e.writeString(c, t.encipher(stored, 0))
+	e.writeString(c, t.encipher(zeroUnits[:t.charset.UnitSize()], len(stored)))
+	traceWrite(e, c, s)
 }
 
-// StringFixed writes fixed string.
+// StringFixed writes fixed string of n code units of the Charset, which are bytes unless it is UTF-16.
+// Longer strings are cut short, which may split a multi-byte character, and shorter ones are padded with zeros.
+// StringPadded reports strings that would be cut instead.
 func (e *Encoder) StringFixed(s string, n int) {
-	if len(s) > n {
-		s = s[:n]
+	t := e.takeText()
+	c := e.begin("StringFixed")
+	stored := e.encodeString(c, t.charset, s)
+	n *= t.charset.UnitSize()
+	if len(stored) > n {
+		stored = stored[:n]
 	}
-	if len(s) < n {
-		s += string(make([]byte, n-len(s)))
+	if len(stored) < n {
+		stored += string(make([]byte, n-len(stored)))
 	}
-	e.Bytes([]byte(s))
+	e.writeString(c, t.encipher(stored, 0))
+	traceWrite(e, c, s)
 }
 
-// StringLenPrefixUint8 writes string with uint8 length prefix.
+// StringLenPrefixUint8 writes string with uint8 length prefix, counting code units of the Charset.
+// A string too long for the prefix records a failure and writes nothing, where earlier versions wrote a truncated length.
 func (e *Encoder) StringLenPrefixUint8(s string) {
-	e.Uint8(uint8(len(s)))
-	e.String(s)
+	t := e.takeText()
+	c := e.begin("StringLenPrefixUint8")
+	stored := e.encodeString(c, t.charset, s)
+	n := len(stored) / t.charset.UnitSize()
+	if uint64(n) > 0xff {
+		e.fail(c, e.Pos(), 0, fmt.Errorf("count %d overflows %d", n, 0xff))
+		return
+	}
+	b := e.fixed(c, 1)
+	b[0] = uint8(n)
+	e.write(c, b)
+	e.writeString(c, t.encipher(stored, 0))
+	traceWrite(e, c, s)
 }
 
-// StringLenPrefixUint16 writes string with uint16 length prefix.
+// StringLenPrefixUint16 writes string with uint16 length prefix, counting code units of the Charset.
+// A string too long for the prefix records a failure and writes nothing, where earlier versions wrote a truncated length.
 func (e *Encoder) StringLenPrefixUint16(s string) {
-	e.Uint16(uint16(len(s)))
-	e.String(s)
+	t := e.takeText()
+	c := e.begin("StringLenPrefixUint16")
+	stored := e.encodeString(c, t.charset, s)
+	n := len(stored) / t.charset.UnitSize()
+	if uint64(n) > 0xffff {
+		e.fail(c, e.Pos(), 0, fmt.Errorf("count %d overflows %d", n, 0xffff))
+		return
+	}
+	b := e.fixed(c, 2)
+	e.order.PutUint16(b, uint16(n))
+	e.write(c, b)
+	e.writeString(c, t.encipher(stored, 0))
+	traceWrite(e, c, s)
 }
 
-// StringLenPrefixUint32 writes string with uint32 length prefix.
+// StringLenPrefixUint32 writes string with uint32 length prefix, counting code units of the Charset.
+// A string too long for the prefix records a failure and writes nothing, where earlier versions wrote a truncated length.
 func (e *Encoder) StringLenPrefixUint32(s string) {
-	e.Uint32(uint32(len(s)))
-	e.String(s)
+	t := e.takeText()
+	c := e.begin("StringLenPrefixUint32")
+	stored := e.encodeString(c, t.charset, s)
+	n := len(stored) / t.charset.UnitSize()
+	if uint64(n) > 0xffffffff {
+		e.fail(c, e.Pos(), 0, fmt.Errorf("count %d overflows %d", n, 0xffffffff))
+		return
+	}
+	b := e.fixed(c, 4)
+	e.order.PutUint32(b, uint32(n))
+	e.write(c, b)
+	e.writeString(c, t.encipher(stored, 0))
+	traceWrite(e, c, s)
 }
 
 // Uint8 writes uint8.
 func (e *Encoder) Uint8(v uint8) {
-	err := binary.Write(e.w, e.order, v)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		e.debugBuf.WriteByte(v)
-	}
-	e.lastPos += 1
+	c := e.begin("Uint8")
+	b := e.fixed(c, 1)
+	b[0] = v
+	e.write(c, b)
+	traceWrite(e, c, v)
 }
 
 // Uint16 writes uint16.
 func (e *Encoder) Uint16(v uint16) {
-	err := binary.Write(e.w, e.order, v)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		e.debugBuf.Write([]byte{byte(v >> 8), byte(v)})
-	}
-	e.lastPos += 2
+	c := e.begin("Uint16")
+	b := e.fixed(c, 2)
+	e.order.PutUint16(b, v)
+	e.write(c, b)
+	traceWrite(e, c, v)
 }
 
 // Uint32 writes uint32.
 func (e *Encoder) Uint32(v uint32) {
-	err := binary.Write(e.w, e.order, v)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		e.debugBuf.Write([]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
-	}
-	e.lastPos += 4
+	c := e.begin("Uint32")
+	b := e.fixed(c, 4)
+	e.order.PutUint32(b, v)
+	e.write(c, b)
+	traceWrite(e, c, v)
 }
 
 // Uint64 writes uint64.
 func (e *Encoder) Uint64(v uint64) {
-	err := binary.Write(e.w, e.order, v)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		e.debugBuf.Write([]byte{byte(v >> 56), byte(v >> 48), byte(v >> 40), byte(v >> 32),
-			byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
-	}
-	e.lastPos += 8
+	c := e.begin("Uint64")
+	b := e.fixed(c, 8)
+	e.order.PutUint64(b, v)
+	e.write(c, b)
+	traceWrite(e, c, v)
 }
 
 // Int8 writes int8.
 func (e *Encoder) Int8(v int8) {
-	err := binary.Write(e.w, e.order, v)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		e.debugBuf.WriteByte(byte(v))
-	}
-	e.lastPos += 1
+	c := e.begin("Int8")
+	b := e.fixed(c, 1)
+	b[0] = byte(v)
+	e.write(c, b)
+	traceWrite(e, c, v)
 }
 
 // Int16 writes int16.
 func (e *Encoder) Int16(v int16) {
-	err := binary.Write(e.w, e.order, v)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		e.debugBuf.Write([]byte{byte(v >> 8), byte(v)})
-	}
-	e.lastPos += 2
+	c := e.begin("Int16")
+	b := e.fixed(c, 2)
+	e.order.PutUint16(b, uint16(v))
+	e.write(c, b)
+	traceWrite(e, c, v)
 }
 
 // Int32 writes int32.
 func (e *Encoder) Int32(v int32) {
-	err := binary.Write(e.w, e.order, v)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		e.debugBuf.Write([]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
-	}
-	e.lastPos += 4
+	c := e.begin("Int32")
+	b := e.fixed(c, 4)
+	e.order.PutUint32(b, uint32(v))
+	e.write(c, b)
+	traceWrite(e, c, v)
 }
 
 // Int64 writes int64.
 func (e *Encoder) Int64(v int64) {
-	err := binary.Write(e.w, e.order, v)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		e.debugBuf.Write([]byte{byte(v >> 56), byte(v >> 48), byte(v >> 40), byte(v >> 32),
-			byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
-	}
-	e.lastPos += 8
+	c := e.begin("Int64")
+	b := e.fixed(c, 8)
+	e.order.PutUint64(b, uint64(v))
+	e.write(c, b)
+	traceWrite(e, c, v)
 }
 
 // Float32 writes float32.
 func (e *Encoder) Float32(v float32) {
-	err := binary.Write(e.w, e.order, v)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		// Convert float32 to uint32 bits and write those bytes
-		bits := math.Float32bits(v)
-		e.debugBuf.Write([]byte{byte(bits >> 24), byte(bits >> 16), byte(bits >> 8), byte(bits)})
-	}
-	e.lastPos += 4
+	c := e.begin("Float32")
+	b := e.fixed(c, 4)
+	e.order.PutUint32(b, math.Float32bits(v))
+	e.write(c, b)
+	traceWrite(e, c, v)
 }
 
 // Float64 writes float64.
 func (e *Encoder) Float64(v float64) {
-	err := binary.Write(e.w, e.order, v)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		bits := math.Float64bits(v)
-		e.debugBuf.Write([]byte{byte(bits >> 56), byte(bits >> 48), byte(bits >> 40), byte(bits >> 32),
-			byte(bits >> 24), byte(bits >> 16), byte(bits >> 8), byte(bits)})
-	}
-	e.lastPos += 8
+	c := e.begin("Float64")
+	b := e.fixed(c, 8)
+	e.order.PutUint64(b, math.Float64bits(v))
+	e.write(c, b)
+	traceWrite(e, c, v)
 }
 
 // Bool writes bool.
 func (e *Encoder) Bool(v bool) {
-	err := binary.Write(e.w, e.order, v)
-	if err != nil {
-		e.lastError = err
-		if e.firstError == nil {
-			e.firstError = e.lastError
-		}
-	}
-	if e.isDebugMode {
-		if v {
-			e.debugBuf.WriteByte(1)
-		} else {
-			e.debugBuf.WriteByte(0)
-		}
+	c := e.begin("Bool")
+	b := e.fixed(c, 1)
+	b[0] = 0
+	if v {
+		b[0] = 1
 	}
-	e.lastPos += 1
+	e.write(c, b)
+	traceWrite(e, c, v)
 }
 
 // LastError returns last error that occurred during write.
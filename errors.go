@@ -0,0 +1,63 @@
+package encdec
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrOverflow is the cause recorded when a variable-length integer does not fit in 64 bits.
+var ErrOverflow = errors.New("varint overflows a 64-bit integer")
+
+// DecodeError is the error recorded by a Decoder when a read fails.
+// Use errors.As on Decoder.Error or Decoder.LastError to inspect it.
+type DecodeError struct {
+	Offset int64  // position the failed read started at, -1 if unknown
+	Op     string // primitive being read, e.g. "Uint32" or "StringZero"
+	Size   int    // number of bytes requested, 0 if not known up front
+	Field  string // caller-supplied label of the value, empty if unlabeled
+	Err    error  // underlying cause
+}
+
+// Error implements the error interface.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("read %s at %s: %v", describeOp(e.Op, e.Field), describeOffset(e.Offset), e.Err)
+}
+
+// Unwrap returns the underlying cause.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// EncodeError is the error recorded by an Encoder when a write fails.
+// Use errors.As on Encoder.Error or Encoder.LastError to inspect it.
+type EncodeError struct {
+	Offset int64  // position the failed write started at, -1 if unknown
+	Op     string // primitive being written, e.g. "Uint32" or "StringZero"
+	Size   int    // number of bytes being written
+	Field  string // caller-supplied label of the value, empty if unlabeled
+	Err    error  // underlying cause
+}
+
+// Error implements the error interface.
+func (e *EncodeError) Error() string {
+	return fmt.Sprintf("write %s at %s: %v", describeOp(e.Op, e.Field), describeOffset(e.Offset), e.Err)
+}
+
+// Unwrap returns the underlying cause.
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+func describeOp(op string, field string) string {
+	if field == "" {
+		return op
+	}
+	return fmt.Sprintf("%s (%s)", field, op)
+}
+
+func describeOffset(offset int64) string {
+	if offset < 0 {
+		return "unknown pos"
+	}
+	return fmt.Sprintf("pos %d (0x%x)", offset, offset)
+}
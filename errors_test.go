@@ -0,0 +1,56 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func TestDecodeError(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader([]byte{0x01, 0x02, 0x03}), binary.LittleEndian)
+	dec.Uint16()
+	dec.Uint32()
+	dec.Uint8()
+
+	var decErr *DecodeError
+	if !errors.As(dec.Error(), &decErr) {
+		t.Fatalf("Error() = %v, want *DecodeError", dec.Error())
+	}
+	if decErr.Offset != 2 || decErr.Op != "Uint32" || decErr.Size != 4 {
+		t.Fatalf("unexpected first error: %+v", decErr)
+	}
+	if !errors.Is(dec.Error(), io.ErrUnexpectedEOF) {
+		t.Fatalf("Error() = %v, want io.ErrUnexpectedEOF", dec.Error())
+	}
+	if dec.Error().Error() != "read Uint32 at pos 2 (0x2): unexpected EOF" {
+		t.Fatalf("unexpected message: %s", dec.Error())
+	}
+	if !errors.As(dec.LastError(), &decErr) || decErr.Op != "Uint8" {
+		t.Fatalf("LastError() = %v, want Uint8 failure", dec.LastError())
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	enc := NewEncoder(failWriter{}, binary.LittleEndian)
+	enc.Uint16(1)
+	enc.StringZero("abc")
+
+	var encErr *EncodeError
+	if !errors.As(enc.Error(), &encErr) {
+		t.Fatalf("Error() = %v, want *EncodeError", enc.Error())
+	}
+	if encErr.Offset != 0 || encErr.Op != "Uint16" || encErr.Size != 2 {
+		t.Fatalf("unexpected first error: %+v", encErr)
+	}
+	if !errors.As(enc.LastError(), &encErr) || encErr.Offset != 5 {
+		t.Fatalf("LastError() = %v, want failure at pos 5", enc.LastError())
+	}
+}
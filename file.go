@@ -0,0 +1,51 @@
+package encdec
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// OpenFile opens the file at path for decoding, such as a large asset archive.
+// On Linux the file is memory-mapped, so Seek is free and, with SetZeroCopy, Bytes returns slices of the mapping.
+// Elsewhere, or if the file cannot be mapped, it is read through io.ReaderAt.
+//
+// Close must be called when done. It releases the mapping, after which zero copy slices must no longer be used,
+// and reads fail with os.ErrClosed.
+func OpenFile(path string, order binary.ByteOrder) (*Decoder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r, closer, err := openFile(f, fi.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	d := NewDecoder(r, order)
+	d.closer = closer
+	return d, nil
+}
+
+// openReaderAt reads f through io.ReaderAt, closing it on close.
+func openReaderAt(f *os.File, size int64) (io.ReadSeeker, func() error, error) {
+	return io.NewSectionReader(f, 0, size), f.Close, nil
+}
+
+// closedStream is the stream of a Decoder after Close, failing every read.
+type closedStream struct{}
+
+// Read fails with os.ErrClosed.
+func (closedStream) Read(p []byte) (int, error) {
+	return 0, os.ErrClosed
+}
+
+// Seek fails with os.ErrClosed.
+func (closedStream) Seek(offset int64, whence int) (int64, error) {
+	return 0, os.ErrClosed
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package encdec
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// openFile memory-maps f read-only, falling back to io.ReaderAt for empty or unmappable files.
+// The file descriptor is not needed once mapped and is closed right away.
+func openFile(f *os.File, size int64) (io.ReadSeeker, func() error, error) {
+	if size <= 0 || size > int64(maxInt) {
+		return openReaderAt(f, size)
+	}
+	b, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return openReaderAt(f, size)
+	}
+	f.Close()
+	m := &memory{b: b}
+	closer := func() error {
+		m.b = nil
+		return syscall.Munmap(b)
+	}
+	return m, closer, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package encdec
+
+import (
+	"io"
+	"os"
+)
+
+// openFile reads f through io.ReaderAt on platforms without mmap support here.
+func openFile(f *os.File, size int64) (io.ReadSeeker, func() error, error) {
+	return openReaderAt(f, size)
+}
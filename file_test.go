@@ -0,0 +1,78 @@
+package encdec
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.bin")
+	data := []byte{
+		'P', 'A', 'K', 0x00, 0x0c, 0x00, 0x00, 0x00, // magic, offset of entry
+		0x00, 0x00, 0x00, 0x00, // padding
+		0x02, 0x00, 0x61, 0x62, // entry
+	}
+	err := os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	dec, err := OpenFile(path, binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	if _, ok := dec.r.(*memory); !ok && runtime.GOOS == "linux" {
+		t.Fatalf("OpenFile did not memory-map on linux, got %T", dec.r)
+	}
+	dec.SetZeroCopy(true)
+	if magic := dec.StringFixed(4); magic != "PAK\x00" {
+		t.Fatalf("magic = %q", magic)
+	}
+	dec.Seek(int64(dec.Uint32()), io.SeekStart)
+	if name := dec.StringLenPrefixUint16(); name != "ab" || dec.Len() != int64(len(data)) {
+		t.Fatalf("name = %q, Len() = %d", name, dec.Len())
+	}
+	if b := dec.Peek(0); dec.Error() != nil || len(b) != 0 {
+		t.Fatalf("Peek(0) = %v, %v", b, dec.Error())
+	}
+
+	if err = dec.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err = dec.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	dec.Uint8()
+	if !errors.Is(dec.LastError(), os.ErrClosed) {
+		t.Fatalf("read after Close: %v, want os.ErrClosed", dec.LastError())
+	}
+
+	_, err = OpenFile(filepath.Join(t.TempDir(), "missing.bin"), binary.LittleEndian)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("OpenFile missing = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestOpenFileEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.bin")
+	err := os.WriteFile(path, nil, 0644)
+	if err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	dec, err := OpenFile(path, binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	dec.Uint8()
+	if !errors.Is(dec.Error(), io.EOF) {
+		t.Fatalf("Error() = %v, want EOF", dec.Error())
+	}
+	if err = dec.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
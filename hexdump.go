@@ -0,0 +1,153 @@
+package encdec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const hexdumpWidth = 16
+
+// hexdumpColors are the ANSI colors cycled through for each field.
+var hexdumpColors = []string{"\x1b[31m", "\x1b[32m", "\x1b[33m", "\x1b[34m", "\x1b[35m", "\x1b[36m"}
+
+const hexdumpReset = "\x1b[0m"
+
+// hexCell is a single byte of a hexdump row.
+type hexCell struct {
+	b     byte
+	set   bool
+	field int
+	start bool
+}
+
+// Hexdump renders the trace as a hexdump with each field annotated by its label and decoded value.
+// Field boundaries are marked with a | in the hex column, and are colored when color is true.
+func (d *Decoder) Hexdump(color bool) string {
+	return hexdump(d.trace.entries, color)
+}
+
+// Hexdump renders the trace as a hexdump with each field annotated by its label and decoded value.
+// Field boundaries are marked with a | in the hex column, and are colored when color is true.
+func (e *Encoder) Hexdump(color bool) string {
+	return hexdump(e.trace.entries, color)
+}
+
+// hexdump renders entries as rows of offset, hex, ASCII and field annotations.
+// Entries of each region read or written through a transform follow those of the stream, under a heading of their own,
+// as their offsets count from the start of the region.
+func hexdump(entries []TraceEntry, color bool) string {
+	regions := []int{}
+	seen := map[int]bool{}
+	for _, entry := range entries {
+		if !seen[entry.Region] {
+			seen[entry.Region] = true
+			regions = append(regions, entry.Region)
+		}
+	}
+	sort.Ints(regions)
+
+	sb := strings.Builder{}
+	for _, region := range regions {
+		if region != 0 {
+			fmt.Fprintf(&sb, "region %d\n", region)
+		}
+		hexdumpRegion(&sb, entries, region, color)
+	}
+	return sb.String()
+}
+
+// hexdumpRegion renders the entries of region, keeping the index of each entry in entries for its color.
+func hexdumpRegion(sb *strings.Builder, entries []TraceEntry, region int, color bool) {
+	rows := map[int64]*[hexdumpWidth]hexCell{}
+	notes := map[int64][]int{}
+	for i, entry := range entries {
+		if entry.Region != region {
+			continue
+		}
+		for j, b := range entry.Raw {
+			pos := entry.Offset + int64(j)
+			row := pos - pos%hexdumpWidth
+			cells, ok := rows[row]
+			if !ok {
+				cells = &[hexdumpWidth]hexCell{}
+				rows[row] = cells
+			}
+			cells[pos%hexdumpWidth] = hexCell{b: b, set: true, field: i, start: j == 0}
+			if j == 0 {
+				notes[row] = append(notes[row], i)
+			}
+		}
+	}
+
+	offsets := make([]int64, 0, len(rows))
+	for row := range rows {
+		offsets = append(offsets, row)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	for i, row := range offsets {
+		if i > 0 && row != offsets[i-1]+hexdumpWidth {
+			sb.WriteString("*\n")
+		}
+		cells := rows[row]
+		fmt.Fprintf(sb, "%08x ", row)
+		for _, cell := range cells {
+			if !cell.set {
+				sb.WriteString("   ")
+				continue
+			}
+			if cell.start {
+				sb.WriteByte('|')
+			} else {
+				sb.WriteByte(' ')
+			}
+			writeColored(sb, fmt.Sprintf("%02x", cell.b), cell.field, color)
+		}
+		sb.WriteString("  |")
+		for _, cell := range cells {
+			if !cell.set {
+				sb.WriteByte(' ')
+				continue
+			}
+			ch := "."
+			if cell.b >= 0x20 && cell.b < 0x7f {
+				ch = string(cell.b)
+			}
+			writeColored(sb, ch, cell.field, color)
+		}
+		sb.WriteString("|")
+		for j, field := range notes[row] {
+			if j == 0 {
+				sb.WriteString("  ")
+			} else {
+				sb.WriteString(", ")
+			}
+			writeColored(sb, hexdumpNote(entries[field]), field, color)
+		}
+		sb.WriteByte('\n')
+	}
+}
+
+// hexdumpNote returns the annotation of a field, e.g. `header.version = 1`.
+func hexdumpNote(entry TraceEntry) string {
+	name := entry.Label
+	if name == "" {
+		name = entry.Op
+	}
+	value := formatValue(entry.Value)
+	if len(value) > 32 {
+		value = value[:29] + "..."
+	}
+	return name + " = " + value
+}
+
+func writeColored(sb *strings.Builder, s string, field int, color bool) {
+	if !color {
+		sb.WriteString(s)
+		return
+	}
+	sb.WriteString(hexdumpColors[field%len(hexdumpColors)])
+	sb.WriteString(s)
+	sb.WriteString(hexdumpReset)
+}
@@ -0,0 +1,39 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestHexdump(t *testing.T) {
+	data := []byte{
+		0x34, 0x12, // version
+		0x01,                   // enabled
+		0x03, 0x61, 0x62, 0x63, // name
+		0x68, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, 0x00, // greeting
+	}
+	dec := NewDecoder(bytes.NewReader(data), binary.LittleEndian)
+	dec.SetDebugMode(true)
+	dec.Field("version").Uint16()
+	dec.Bool()
+	dec.Field("name").StringLenPrefixUint8()
+	dec.Field("greeting").StringZero()
+
+	want := `00000000 |34 12|01|03 61 62 63|68 65 6c 6c 6f 20 77 6f 72  |4...abchello wor|  version = 4660, Bool = true, name = "abc", greeting = "hello world"
+00000010  6c 64 00                                         |ld.             |
+`
+	if got := dec.Hexdump(false); got != want {
+		t.Fatalf("Hexdump() =\n%s\nwant\n%s", got, want)
+	}
+
+	w := bytes.NewBuffer(nil)
+	enc := NewEncoder(w, binary.LittleEndian)
+	enc.SetDebugMode(true)
+	enc.Field("version").Uint16(0x1234)
+	want = "00000000 |\x1b[31m34\x1b[0m \x1b[31m12\x1b[0m" + `                                            |` +
+		"\x1b[31m4\x1b[0m\x1b[31m.\x1b[0m" + `              |  ` + "\x1b[31mversion = 4660\x1b[0m\n"
+	if got := enc.Hexdump(true); got != want {
+		t.Fatalf("Hexdump() =\n%q\nwant\n%q", got, want)
+	}
+}
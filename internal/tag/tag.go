@@ -0,0 +1,184 @@
+// Package tag parses the encdec struct tags shared by the reflection codec and encdecgen.
+//
+// A tag is a comma separated list of options, e.g. `encdec:"u16,le"`, `encdec:"str,zero"` or `encdec:"len=Count"`:
+//
+//	u8 u16 u32 u64 i8 i16 i32 i64 f32 f64 bool  fixed width value, inferred from the Go type if omitted
+//	uvarint varint uleb128 sleb128              variable length integer
+//	str bytes                                    string or []byte, inferred from the Go type if omitted
+//	le be                                        byte order for this field only
+//	zero                                         string, or each string of a slice or array, is zero terminated
+//	len=N                                        string, bytes or slice has N bytes or elements
+//	len=Field                                    string, bytes or slice length is the value of an earlier integer field
+//	prefix=u8|u16|u32|uvarint                    string, bytes or slice length is written just before it
+//	-                                            field is skipped
+package tag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tag is a parsed encdec struct tag.
+type Tag struct {
+	Kind   string // value kind, e.g. "u16" or "str", empty to infer from the Go type
+	Order  string // "le" or "be", empty for the Decoder or Encoder order
+	Zero   bool   // string, or each string of a slice or array, is zero terminated
+	Len    string // fixed count or name of the field holding the count
+	Prefix string // kind of the inline count prefix
+	Skip   bool   // field is skipped
+}
+
+// kinds maps each value kind to the Decoder and Encoder method that handles it.
+var kinds = map[string]string{
+	"u8":      "Uint8",
+	"u16":     "Uint16",
+	"u32":     "Uint32",
+	"u64":     "Uint64",
+	"i8":      "Int8",
+	"i16":     "Int16",
+	"i32":     "Int32",
+	"i64":     "Int64",
+	"f32":     "Float32",
+	"f64":     "Float64",
+	"bool":    "Bool",
+	"uvarint": "Uvarint",
+	"varint":  "Varint",
+	"uleb128": "ULEB128",
+	"sleb128": "SLEB128",
+	"str":     "",
+	"bytes":   "",
+}
+
+// goKinds maps Go type names to their default value kind.
+var goKinds = map[string]string{
+	"uint8":   "u8",
+	"byte":    "u8",
+	"uint16":  "u16",
+	"uint32":  "u32",
+	"uint64":  "u64",
+	"int8":    "i8",
+	"int16":   "i16",
+	"int32":   "i32",
+	"int64":   "i64",
+	"float32": "f32",
+	"float64": "f64",
+	"bool":    "bool",
+	"string":  "str",
+}
+
+// prefixes are the kinds allowed for an inline count prefix.
+var prefixes = map[string]bool{
+	"u8":      true,
+	"u16":     true,
+	"u32":     true,
+	"uvarint": true,
+}
+
+// Parse parses the value of an encdec struct tag.
+func Parse(s string) (Tag, error) {
+	t := Tag{}
+	if s == "" {
+		return t, nil
+	}
+	for _, opt := range strings.Split(s, ",") {
+		opt = strings.TrimSpace(opt)
+		key, value, hasValue := strings.Cut(opt, "=")
+		switch {
+		case opt == "-":
+			t.Skip = true
+		case opt == "le" || opt == "be":
+			t.Order = opt
+		case opt == "zero":
+			t.Zero = true
+		case key == "len" && hasValue:
+			if value == "" {
+				return t, fmt.Errorf("empty len in %q", s)
+			}
+			t.Len = value
+		case key == "prefix" && hasValue:
+			if !prefixes[value] {
+				return t, fmt.Errorf("unknown prefix %q in %q", value, s)
+			}
+			t.Prefix = value
+		default:
+			if _, ok := kinds[opt]; !ok || hasValue {
+				return t, fmt.Errorf("unknown option %q in %q", opt, s)
+			}
+			if t.Kind != "" {
+				return t, fmt.Errorf("multiple kinds %q and %q in %q", t.Kind, opt, s)
+			}
+			t.Kind = opt
+		}
+	}
+	if t.Len != "" && t.Prefix != "" {
+		return t, fmt.Errorf("len and prefix are exclusive in %q", s)
+	}
+	return t, nil
+}
+
+// CheckString returns an error if t cannot describe a string.
+// A zero terminated string has no count, so zero with len or prefix is only allowed on a slice or array,
+// where the count is that of the elements and zero applies to each string.
+func (t Tag) CheckString() error {
+	if t.Zero && (t.Len != "" || t.Prefix != "") {
+		return fmt.Errorf("zero cannot be combined with len or prefix on a string, only on a slice of strings")
+	}
+	return nil
+}
+
+// Count returns the fixed count of a len=N option.
+func (t Tag) Count() (int, bool) {
+	n, err := strconv.Atoi(t.Len)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// LenField returns the name of the field holding the count of a len=Field option.
+func (t Tag) LenField() (string, bool) {
+	if t.Len == "" {
+		return "", false
+	}
+	if _, ok := t.Count(); ok {
+		return "", false
+	}
+	return t.Len, true
+}
+
+// Infer returns the default value kind of a Go type name such as "uint16", or false if it has none.
+func Infer(goType string) (string, bool) {
+	kind, ok := goKinds[goType]
+	return kind, ok
+}
+
+// goTypes maps each fixed width or varint kind to the Go type its Decoder method returns.
+var goTypes = map[string]string{
+	"u8":      "uint8",
+	"u16":     "uint16",
+	"u32":     "uint32",
+	"u64":     "uint64",
+	"i8":      "int8",
+	"i16":     "int16",
+	"i32":     "int32",
+	"i64":     "int64",
+	"f32":     "float32",
+	"f64":     "float64",
+	"bool":    "bool",
+	"uvarint": "uint64",
+	"varint":  "int64",
+	"uleb128": "uint64",
+	"sleb128": "int64",
+}
+
+// GoType returns the Go type name the Decoder method of a fixed width or varint kind returns, e.g. "uint16" for "u16".
+func GoType(kind string) string {
+	return goTypes[kind]
+}
+
+// Method returns the Decoder and Encoder method that handles a fixed width or varint kind, e.g. "Uint16" for "u16".
+func Method(kind string) (string, bool) {
+	method, ok := kinds[kind]
+	return method, ok && method != ""
+}
@@ -0,0 +1,46 @@
+package tag
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Tag
+		wantErr bool
+	}{
+		{in: "", want: Tag{}},
+		{in: "u16,le", want: Tag{Kind: "u16", Order: "le"}},
+		{in: "str,zero", want: Tag{Kind: "str", Zero: true}},
+		{in: "len=Count", want: Tag{Len: "Count"}},
+		{in: "prefix=uvarint", want: Tag{Prefix: "uvarint"}},
+		{in: "-", want: Tag{Skip: true}},
+		{in: "u17", wantErr: true},
+		{in: "u16,u32", wantErr: true},
+		{in: "prefix=u64", wantErr: true},
+		{in: "len=4,prefix=u8", wantErr: true},
+		{in: "zero,len=4", want: Tag{Zero: true, Len: "4"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if err == nil && got != tt.want {
+			t.Fatalf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+
+	if err := (Tag{Zero: true, Prefix: "u8"}).CheckString(); err == nil {
+		t.Fatalf("CheckString() of zero,prefix=u8 = nil, want error")
+	}
+	if err := (Tag{Zero: true}).CheckString(); err != nil {
+		t.Fatalf("CheckString() of zero = %v", err)
+	}
+
+	if n, ok := (Tag{Len: "4"}).Count(); !ok || n != 4 {
+		t.Fatalf("Count() = %d, %v, want 4", n, ok)
+	}
+	if name, ok := (Tag{Len: "Count"}).LenField(); !ok || name != "Count" {
+		t.Fatalf("LenField() = %q, %v, want Count", name, ok)
+	}
+}
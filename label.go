@@ -0,0 +1,67 @@
+package encdec
+
+import (
+	"strconv"
+	"strings"
+)
+
+// call describes a single Decoder or Encoder method call in progress.
+type call struct {
+	op     string
+	label  string
+	pos    int64
+	region int
+}
+
+// fieldPath tracks nested scopes and the label of the next call.
+type fieldPath struct {
+	scopes []string
+	next   string
+}
+
+// push enters a nested scope.
+func (p *fieldPath) push(name string) {
+	p.scopes = append(p.scopes, name)
+}
+
+// pop leaves the innermost scope, if any.
+func (p *fieldPath) pop() {
+	if len(p.scopes) == 0 {
+		return
+	}
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+// take returns the full label of the next call and clears the pending field.
+func (p *fieldPath) take() string {
+	if len(p.scopes) == 0 && p.next == "" {
+		return ""
+	}
+	label := p.join(p.next)
+	p.next = ""
+	return label
+}
+
+// join renders scopes followed by name, e.g. "someSubStructs[1].val3.x".
+func (p *fieldPath) join(name string) string {
+	sb := strings.Builder{}
+	for _, scope := range p.scopes {
+		appendLabel(&sb, scope)
+	}
+	appendLabel(&sb, name)
+	return sb.String()
+}
+
+func appendLabel(sb *strings.Builder, name string) {
+	if name == "" {
+		return
+	}
+	if sb.Len() > 0 && name[0] != '[' {
+		sb.WriteByte('.')
+	}
+	sb.WriteString(name)
+}
+
+func indexLabel(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
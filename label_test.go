@@ -0,0 +1,64 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestLabel(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader([]byte{
+		0x01, 0x00, // version
+		0x02, 0x00, 0x00, 0x00, // someSubStructs length
+		0x00, 0x00, 0x80, 0x3f, // someSubStructs[0].val3.x
+		0x00, 0x00, // someSubStructs[1].val3.x (truncated)
+	}), binary.LittleEndian)
+	dec.SetDebugMode(true)
+
+	dec.Scope("header")
+	dec.Field("version").Uint16()
+	dec.EndScope()
+
+	count := dec.Field("someSubStructs").Uint32()
+	dec.Scope("someSubStructs")
+	for i := 0; i < int(count); i++ {
+		dec.Index(i)
+		dec.Scope("val3")
+		if dec.Label() != "someSubStructs"+indexLabel(i)+".val3" {
+			t.Fatalf("Label() = %q", dec.Label())
+		}
+		dec.Field("x").Float32()
+		dec.EndScope()
+		dec.EndScope()
+	}
+	dec.EndScope()
+
+	var decErr *DecodeError
+	if !errors.As(dec.Error(), &decErr) {
+		t.Fatalf("Error() = %v, want *DecodeError", dec.Error())
+	}
+	if decErr.Field != "someSubStructs[1].val3.x" || decErr.Offset != 10 {
+		t.Fatalf("unexpected error: %+v", decErr)
+	}
+	if dec.Label() != "" {
+		t.Fatalf("Label() = %q after closing all scopes", dec.Label())
+	}
+
+	entries := dec.Trace()
+	if len(entries) != 4 {
+		t.Fatalf("Trace() = %+v, want 4 entries", entries)
+	}
+	if entries[0].Label != "header.version" || entries[2].Label != "someSubStructs[0].val3.x" {
+		t.Fatalf("unexpected trace labels: %q, %q", entries[0].Label, entries[2].Label)
+	}
+
+	enc := NewEncoder(failWriter{}, binary.LittleEndian)
+	enc.Scope("header")
+	enc.Field("name").StringLenPrefixUint8("abc")
+	enc.EndScope()
+	var encErr *EncodeError
+	if !errors.As(enc.Error(), &encErr) || encErr.Field != "header.name" || encErr.Op != "StringLenPrefixUint8" {
+		t.Fatalf("Error() = %v, want header.name failure", enc.Error())
+	}
+}
@@ -0,0 +1,95 @@
+package encdec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrLimitExceeded is the cause recorded when a read would break one of the Decoder's Limits.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// sanityCheckSize is the allocation size above which reads are first checked against the remaining stream length.
+const sanityCheckSize = 64 * 1024
+
+// Limits bounds what a Decoder will allocate and read, to protect against corrupt or hostile length prefixes.
+// A zero value means no limit.
+type Limits struct {
+	MaxAlloc  int   // largest single allocation, such as Bytes(n)
+	MaxTotal  int64 // total bytes read over the life of the Decoder
+	MaxString int   // longest string returned by the String methods
+}
+
+// SetLimits sets the limits checked before each read.
+// Regardless of limits, reads larger than 64KiB are checked against the remaining length of the stream when it can be determined.
+func (d *Decoder) SetLimits(limits Limits) {
+	d.limits = limits
+}
+
+// Limits returns the limits set by SetLimits.
+func (d *Decoder) Limits() Limits {
+	return d.limits
+}
+
+// checkAlloc reports whether n bytes may be allocated and read, recording a failure if not.
+func (d *Decoder) checkAlloc(c call, pos int64, n int) bool {
+	if n < 0 {
+		d.fail(c, pos, n, fmt.Errorf("negative size %d", n))
+		return false
+	}
+	if d.limits.MaxAlloc > 0 && n > d.limits.MaxAlloc {
+		d.fail(c, pos, n, fmt.Errorf("%w: %d bytes exceeds max allocation of %d", ErrLimitExceeded, n, d.limits.MaxAlloc))
+		return false
+	}
+	if d.limits.MaxTotal > 0 && d.total+int64(n) > d.limits.MaxTotal {
+		d.fail(c, pos, n, fmt.Errorf("%w: %d bytes exceeds max total of %d", ErrLimitExceeded, d.total+int64(n), d.limits.MaxTotal))
+		return false
+	}
+	if n > sanityCheckSize {
+		remaining := d.Remaining()
+		if remaining >= 0 && int64(n) > remaining {
+			d.fail(c, pos, n, fmt.Errorf("%w: %d bytes requested, %d remaining", io.ErrUnexpectedEOF, n, remaining))
+			return false
+		}
+	}
+	return true
+}
+
+// checkString reports whether a string of n bytes may be read, recording a failure if not.
+func (d *Decoder) checkString(c call, pos int64, n int) bool {
+	if d.limits.MaxString > 0 && n > d.limits.MaxString {
+		d.fail(c, pos, n, fmt.Errorf("%w: %d byte string exceeds max string length of %d", ErrLimitExceeded, n, d.limits.MaxString))
+		return false
+	}
+	return true
+}
+
+// stringBytes reads the n bytes of a string, recording any failure.
+func (d *Decoder) stringBytes(c call, n int) []byte {
+	if !d.checkString(c, d.Pos(), n) {
+		return nil
+	}
+	return d.bytes(c, n)
+}
+
+// budgetReader counts bytes read from a Decoder's stream, failing once Limits.MaxTotal is reached.
+type budgetReader struct {
+	d *Decoder
+}
+
+// Read reads from the Decoder's stream up to the remaining budget.
+func (b budgetReader) Read(p []byte) (int, error) {
+	d := b.d
+	if d.limits.MaxTotal > 0 {
+		left := d.limits.MaxTotal - d.total
+		if left <= 0 {
+			return 0, fmt.Errorf("%w: max total of %d bytes read", ErrLimitExceeded, d.limits.MaxTotal)
+		}
+		if int64(len(p)) > left {
+			p = p[:left]
+		}
+	}
+	n, err := d.r.Read(p)
+	d.total += int64(n)
+	return n, err
+}
@@ -0,0 +1,69 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	hostile := []byte{0xff, 0xff, 0xff, 0xff, 'a', 'b', 'c'}
+	dec := NewDecoder(bytes.NewReader(hostile), binary.LittleEndian)
+	if s := dec.StringLenPrefixUint32(); s != "" {
+		t.Fatalf("StringLenPrefixUint32() = %q, want empty", s)
+	}
+	var decErr *DecodeError
+	if !errors.Is(dec.Error(), io.ErrUnexpectedEOF) || !errors.As(dec.Error(), &decErr) || decErr.Size != 0xffffffff {
+		t.Fatalf("Error() = %v, want unexpected EOF for 4GB string", dec.Error())
+	}
+
+	tests := []struct {
+		name   string
+		limits Limits
+		read   func(dec *Decoder)
+	}{
+		{name: "alloc", limits: Limits{MaxAlloc: 2}, read: func(dec *Decoder) { dec.Bytes(3) }},
+		{name: "string", limits: Limits{MaxString: 2}, read: func(dec *Decoder) { dec.StringFixed(3) }},
+		{name: "string zero", limits: Limits{MaxString: 2}, read: func(dec *Decoder) { dec.StringZero() }},
+		{name: "total", limits: Limits{MaxTotal: 5}, read: func(dec *Decoder) { dec.Uint32(); dec.Uint16() }},
+		{name: "total bytes", limits: Limits{MaxTotal: 5}, read: func(dec *Decoder) { dec.Uint32(); dec.Bytes(2) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader([]byte("abcdefgh\x00")), binary.LittleEndian)
+			dec.SetLimits(tt.limits)
+			tt.read(dec)
+			if !errors.Is(dec.Error(), ErrLimitExceeded) {
+				t.Fatalf("Error() = %v, want ErrLimitExceeded", dec.Error())
+			}
+		})
+	}
+
+	dec = NewDecoder(bytes.NewReader([]byte("abcdefgh")), binary.LittleEndian)
+	dec.SetLimits(Limits{MaxTotal: 6})
+	sub := dec.Sub(4)
+	sub.Uint32()
+	if err := sub.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	dec.Uint32()
+	if !errors.Is(dec.Error(), ErrLimitExceeded) {
+		t.Fatalf("Error() = %v, want section reads to count toward MaxTotal", dec.Error())
+	}
+
+	// peeked bytes count only once read, whether the stream can peek or seeks back
+	for _, peekable := range []bool{false, true} {
+		dec = NewDecoder(bytes.NewReader([]byte("abcd")), binary.LittleEndian)
+		if peekable {
+			dec = NewReaderDecoder(io.MultiReader(bytes.NewReader([]byte("abcd"))), binary.LittleEndian)
+		}
+		dec.SetLimits(Limits{MaxTotal: 4})
+		dec.Peek(4)
+		dec.Uint32()
+		if dec.Error() != nil {
+			t.Fatalf("peekable %v: Error() = %v after peeking and reading the whole budget", peekable, dec.Error())
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package encdec
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// errLZ4Corrupt is returned for LZ4 blocks that do not decode to exactly the expected size.
+var errLZ4Corrupt = errors.New("corrupt lz4 block")
+
+const (
+	lz4MinMatch     = 4
+	lz4LastLiterals = 5  // the last bytes of a block are always literals
+	lz4MatchLimit   = 12 // no match starts within this many bytes of the end
+	lz4MaxOffset    = 65535
+	lz4HashLog      = 16
+)
+
+// lz4Decode decodes the LZ4 block src into dst, which must be filled exactly.
+func lz4Decode(dst, src []byte) error {
+	si, di := 0, 0
+	for {
+		if si >= len(src) {
+			return errLZ4Corrupt
+		}
+		token := src[si]
+		si++
+		lit, ok := lz4Length(src, &si, int(token>>4))
+		if !ok || lit > len(src)-si || lit > len(dst)-di {
+			return errLZ4Corrupt
+		}
+		copy(dst[di:], src[si:si+lit])
+		si += lit
+		di += lit
+		if si == len(src) {
+			break
+		}
+
+		if len(src)-si < 2 {
+			return errLZ4Corrupt
+		}
+		offset := int(binary.LittleEndian.Uint16(src[si:]))
+		si += 2
+		n, ok := lz4Length(src, &si, int(token&0x0f))
+		n += lz4MinMatch
+		if !ok || offset == 0 || offset > di || n > len(dst)-di {
+			return errLZ4Corrupt
+		}
+		// matches may overlap the bytes they produce, repeating a short run
+		for i := 0; i < n; i++ {
+			dst[di+i] = dst[di-offset+i]
+		}
+		di += n
+	}
+	if di != len(dst) {
+		return errLZ4Corrupt
+	}
+	return nil
+}
+
+// lz4Length returns a literal or match length starting from the 4 bits n in a token,
+// continued by bytes at src[*si] while the length is saturated.
+func lz4Length(src []byte, si *int, n int) (int, bool) {
+	if n != 0x0f {
+		return n, true
+	}
+	for {
+		if *si >= len(src) {
+			return 0, false
+		}
+		b := src[*si]
+		*si++
+		n += int(b)
+		if b != 0xff {
+			return n, true
+		}
+	}
+}
+
+// lz4Encode returns src compressed as an LZ4 block, finding matches through a hash table of recent 4 byte sequences.
+func lz4Encode(src []byte) []byte {
+	dst := make([]byte, 0, len(src)+len(src)/255+16)
+	anchor := 0
+	if len(src) > lz4MatchLimit {
+		table := make([]int32, 1<<lz4HashLog) // position + 1 of the last sequence with each hash
+		for i := 0; i < len(src)-lz4MatchLimit; {
+			seq := binary.LittleEndian.Uint32(src[i:])
+			h := (seq * 2654435761) >> (32 - lz4HashLog)
+			cand := int(table[h]) - 1
+			table[h] = int32(i + 1)
+			if cand < 0 || i-cand > lz4MaxOffset || binary.LittleEndian.Uint32(src[cand:]) != seq {
+				i++
+				continue
+			}
+			n := lz4MinMatch
+			for i+n < len(src)-lz4LastLiterals && src[cand+n] == src[i+n] {
+				n++
+			}
+			dst = lz4Sequence(dst, src[anchor:i], i-cand, n)
+			i += n
+			anchor = i
+		}
+	}
+	return lz4Sequence(dst, src[anchor:], 0, 0)
+}
+
+// lz4Sequence appends literals followed by a match of n bytes at offset, or the final literals when n is 0.
+func lz4Sequence(dst, literals []byte, offset, n int) []byte {
+	token := lz4Nibble(len(literals)) << 4
+	if n > 0 {
+		token |= lz4Nibble(n - lz4MinMatch)
+	}
+	dst = append(dst, token)
+	dst = lz4AppendLength(dst, len(literals))
+	dst = append(dst, literals...)
+	if n == 0 {
+		return dst
+	}
+	dst = append(dst, byte(offset), byte(offset>>8))
+	return lz4AppendLength(dst, n-lz4MinMatch)
+}
+
+// lz4Nibble returns the 4 bits of a token holding n, saturated at 15.
+func lz4Nibble(n int) byte {
+	if n > 0x0f {
+		return 0x0f
+	}
+	return byte(n)
+}
+
+// lz4AppendLength appends the bytes continuing a length of n that saturated its 4 bits in the token.
+func lz4AppendLength(dst []byte, n int) []byte {
+	if n < 0x0f {
+		return dst
+	}
+	for n -= 0x0f; n >= 0xff; n -= 0xff {
+		dst = append(dst, 0xff)
+	}
+	return append(dst, byte(n))
+}
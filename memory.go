@@ -0,0 +1,137 @@
+package encdec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// NewBytesDecoder returns a Decoder reading from b, such as an already loaded file.
+// Combined with SetZeroCopy, Bytes returns sub-slices of b instead of copies.
+// Any number of Decoders may read the same b concurrently.
+func NewBytesDecoder(b []byte, order binary.ByteOrder) *Decoder {
+	return NewDecoder(&memory{b: b}, order)
+}
+
+// NewReaderAtDecoder returns a Decoder reading the first size bytes of r.
+// Each Decoder keeps its own position, so several can read different regions of the same r concurrently
+// as long as r supports concurrent ReadAt calls, as os.File does.
+func NewReaderAtDecoder(r io.ReaderAt, size int64, order binary.ByteOrder) *Decoder {
+	return NewDecoder(io.NewSectionReader(r, 0, size), order)
+}
+
+// SetZeroCopy makes Bytes return a sub-slice of the underlying memory instead of a copy,
+// for Decoders returned by NewBytesDecoder or OpenFile and their sections.
+// The returned slices must not be modified, and for OpenFile are only valid until Close.
+// Other Decoders ignore the setting.
+func (d *Decoder) SetZeroCopy(value bool) {
+	d.isZeroCopy = value
+}
+
+// IsZeroCopy returns if zero copy is enabled.
+func (d *Decoder) IsZeroCopy() bool {
+	return d.isZeroCopy
+}
+
+// slicer is implemented by in-memory streams that can return the next n bytes without copying.
+type slicer interface {
+	slice(n int) ([]byte, error)
+}
+
+// memory is an io.ReadSeeker over a byte slice.
+type memory struct {
+	b   []byte
+	off int64
+}
+
+// Read reads from the slice.
+func (m *memory) Read(p []byte) (int, error) {
+	if m.off >= int64(len(m.b)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.b[m.off:])
+	m.off += int64(n)
+	return n, nil
+}
+
+// Seek moves within the slice. Seeking past the end is allowed, and reads there return io.EOF.
+func (m *memory) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = m.off + offset
+	case io.SeekEnd:
+		abs = int64(len(m.b)) + offset
+	default:
+		return m.off, fmt.Errorf("invalid whence %d", whence)
+	}
+	if abs < 0 {
+		return m.off, fmt.Errorf("negative position %d", abs)
+	}
+	m.off = abs
+	return abs, nil
+}
+
+// size returns the length of the slice.
+func (m *memory) size() (int64, bool) {
+	return int64(len(m.b)), true
+}
+
+// slice returns the next n bytes of the slice without copying, or as many as are left with io.ErrUnexpectedEOF.
+func (m *memory) slice(n int) ([]byte, error) {
+	if m.off >= int64(len(m.b)) {
+		if n == 0 {
+			return nil, nil
+		}
+		return nil, io.EOF
+	}
+	left := int64(len(m.b)) - m.off
+	var err error
+	if int64(n) > left {
+		n = int(left)
+		err = io.ErrUnexpectedEOF
+	}
+	b := m.b[m.off : m.off+int64(n) : m.off+int64(n)]
+	m.off += int64(n)
+	return b, err
+}
+
+// slice returns the next n bytes of the section without copying.
+// It must only be used if canSlice reports true for the section.
+func (s *section) slice(n int) ([]byte, error) {
+	if int64(n) > s.n-s.off {
+		b, _ := s.r.(slicer).slice(int(s.n - s.off))
+		s.off += int64(len(b))
+		return b, ErrSectionOverrun
+	}
+	b, err := s.r.(slicer).slice(n)
+	s.off += int64(len(b))
+	return b, err
+}
+
+// canSlice reports whether r is, or is a section of, memory that Bytes can slice instead of copy.
+func canSlice(r io.Reader) bool {
+	switch r := r.(type) {
+	case *memory:
+		return true
+	case *section:
+		return canSlice(r.r)
+	}
+	return false
+}
+
+// sliceBytes returns the next n bytes of memory that canSlice without copying, recording any failure.
+// Unlike a copying read, a short read returns only the bytes that were available.
+func (d *Decoder) sliceBytes(c call, pos int64, n int) []byte {
+	b, err := d.r.(slicer).slice(n)
+	d.total += int64(len(b))
+	if d.isDebugMode {
+		d.trace.pending.Write(b)
+	}
+	if err != nil {
+		d.fail(c, pos, n, err)
+	}
+	return b
+}
@@ -0,0 +1,86 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestBytesDecoder(t *testing.T) {
+	data := []byte{
+		0x03, 0x00, 0x61, 0x62, 0x63, // u16 prefixed name
+		0x04, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04, // section
+		0x05, 0x06, // tail
+	}
+	dec := NewBytesDecoder(data, binary.LittleEndian)
+	dec.SetDebugMode(true)
+	dec.SetZeroCopy(true)
+	if name := dec.StringLenPrefixUint16(); name != "abc" {
+		t.Fatalf("name = %q, want abc", name)
+	}
+	size := dec.Uint32()
+	sub := dec.Field("chunk").Sub(int64(size))
+	b := sub.Bytes(4)
+	if &b[0] != &data[9] || cap(b) != 4 {
+		t.Fatalf("section Bytes(4) was copied")
+	}
+	if err := sub.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	tail := dec.Bytes(4)
+	var decErr *DecodeError
+	if !bytes.Equal(tail, []byte{0x05, 0x06}) || !errors.Is(dec.Error(), io.ErrUnexpectedEOF) || !errors.As(dec.Error(), &decErr) || decErr.Offset != 13 {
+		t.Fatalf("Bytes(4) at tail = % x, %v, want 05 06 and unexpected EOF at 13", tail, dec.Error())
+	}
+	if entries := dec.Trace(); len(entries) != 4 || entries[2].Label != "chunk" || !bytes.Equal(entries[2].Raw, data[9:13]) {
+		t.Fatalf("unexpected trace: %+v", entries)
+	}
+
+	// without zero copy, Bytes does not alias the input
+	dec = NewBytesDecoder(data, binary.LittleEndian)
+	dec.Skip(9)
+	if b = dec.Bytes(4); &b[0] == &data[9] {
+		t.Fatalf("Bytes(4) aliases input without SetZeroCopy")
+	}
+}
+
+func TestReaderAtDecoder(t *testing.T) {
+	data := make([]byte, 4*256)
+	for i := 0; i < 256; i++ {
+		binary.BigEndian.PutUint32(data[i*4:], uint32(i))
+	}
+	r := bytes.NewReader(data)
+
+	// each Decoder has its own cursor over the shared ReaderAt
+	wg := sync.WaitGroup{}
+	errs := make([]error, 4)
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			dec := NewReaderAtDecoder(r, int64(len(data)), binary.BigEndian)
+			dec.Seek(int64(g*64*4), io.SeekStart)
+			for i := 0; i < 64; i++ {
+				if v := dec.Uint32(); v != uint32(g*64+i) {
+					errs[g] = errors.New("wrong value")
+					return
+				}
+			}
+			errs[g] = dec.Error()
+		}(g)
+	}
+	wg.Wait()
+	for g, err := range errs {
+		if err != nil {
+			t.Fatalf("decoder %d: %v", g, err)
+		}
+	}
+
+	dec := NewReaderAtDecoder(r, 8, binary.BigEndian)
+	if dec.Len() != 8 {
+		t.Fatalf("Len() = %d, want 8", dec.Len())
+	}
+}
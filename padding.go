@@ -0,0 +1,110 @@
+package encdec
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrBadPadding is the cause recorded when StringPadded with PadZeroStrict finds non-zero bytes after the terminator.
+var ErrBadPadding = errors.New("non-zero bytes after string terminator")
+
+// ErrTruncated is the cause recorded when StringPadded is given a string that would not be read back whole,
+// because it is too long for the field or holds characters that end it early.
+var ErrTruncated = errors.New("string would be truncated")
+
+// Padding is how StringPadded fills the unused end of a fixed size string field.
+type Padding int
+
+const (
+	// PadZero ends the string at the first zero code unit, ignoring whatever follows.
+	PadZero Padding = iota
+	// PadSpace fills the field with trailing spaces, which are trimmed when read.
+	PadSpace
+	// PadZeroStrict ends the string at the first zero code unit, and requires everything after it to be zero.
+	PadZeroStrict
+)
+
+// String returns the name of the padding.
+func (p Padding) String() string {
+	switch p {
+	case PadZero:
+		return "PadZero"
+	case PadSpace:
+		return "PadSpace"
+	case PadZeroStrict:
+		return "PadZeroStrict"
+	}
+	return fmt.Sprintf("Padding(%d)", int(p))
+}
+
+// StringPadded returns a string stored in a field of n code units of the Charset, with its padding removed.
+// Unlike StringFixed, the padding and anything after a zero terminator are not part of the string.
+func (d *Decoder) StringPadded(n int, pad Padding) string {
+	t := d.takeText()
+	c := d.begin("StringPadded")
+	b := t.decipher(d.stringBytes(c, n*t.charset.UnitSize()))
+	text, rest := b, []byte(nil)
+	if pad != PadSpace {
+		text, rest = cutZeroUnit(b, t.charset.UnitSize())
+	}
+	value, err := t.charset.Decode(text)
+	if err == nil && pad == PadZeroStrict {
+		for _, v := range rest {
+			if v != 0 {
+				err = ErrBadPadding
+				break
+			}
+		}
+	}
+	if err != nil {
+		d.fail(c, d.Pos()-int64(len(b)), len(b), err)
+	}
+	if pad == PadSpace {
+		value = strings.TrimRight(value, " ")
+	}
+	traceRead(d, c, value)
+	return value
+}
+
+// cutZeroUnit splits b at its first zero code unit, returning the bytes before and after it.
+func cutZeroUnit(b []byte, unit int) (text, rest []byte) {
+	for i := 0; i+unit <= len(b); i += unit {
+		if string(b[i:i+unit]) == zeroUnits[:unit] {
+			return b[:i], b[i+unit:]
+		}
+	}
+	return b, nil
+}
+
+// StringPadded writes s in a field of n code units of the Charset, filling the rest as pad describes.
+// Unlike StringFixed, a string that would not be read back whole by the Decoder's StringPadded records ErrTruncated:
+// one too long for the field, ending in a space with PadSpace, or holding a zero character otherwise.
+// The field is still written, cut to n units, so later writes stay in place.
+func (e *Encoder) StringPadded(s string, n int, pad Padding) {
+	t := e.takeText()
+	c := e.begin("StringPadded")
+	unit := t.charset.UnitSize()
+	size := n * unit
+	stored := e.encodeString(c, t.charset, s)
+	switch {
+	case len(stored) > size:
+		e.fail(c, e.Pos(), size, fmt.Errorf("%w: %d bytes do not fit in %d", ErrTruncated, len(stored), size))
+	case pad == PadSpace && strings.HasSuffix(s, " "):
+		e.fail(c, e.Pos(), size, fmt.Errorf("%w: trailing space would be trimmed", ErrTruncated))
+	case pad != PadSpace && strings.Contains(s, "\x00"):
+		e.fail(c, e.Pos(), size, fmt.Errorf("%w: zero character would end the string", ErrTruncated))
+	}
+	if pad == PadSpace && len(stored) < size {
+		// spaces are encoded with the string, as a space is one code unit in the built-in Charsets
+		stored = e.encodeString(c, t.charset, s+strings.Repeat(" ", (size-len(stored))/unit))
+	}
+	if len(stored) > size {
+		stored = stored[:size]
+	}
+	if len(stored) < size {
+		stored += string(make([]byte, size-len(stored)))
+	}
+	e.writeString(c, t.encipher(stored, 0))
+	traceWrite(e, c, s)
+}
@@ -0,0 +1,74 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestStringPadded(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		pad  Padding
+		want string
+		err  error
+	}{
+		{"zero", []byte{'a', 'b', 0, 'x', 'y'}, PadZero, "ab", nil},
+		{"zero full", []byte{'a', 'b', 'c', 'd', 'e'}, PadZero, "abcde", nil},
+		{"space", []byte{'a', ' ', 'b', ' ', ' '}, PadSpace, "a b", nil},
+		{"strict", []byte{'a', 'b', 0, 0, 0}, PadZeroStrict, "ab", nil},
+		{"strict garbage", []byte{'a', 'b', 0, 'x', 0}, PadZeroStrict, "ab", ErrBadPadding},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewBytesDecoder(tt.data, binary.LittleEndian)
+			got := dec.Field("name").StringPadded(5, tt.pad)
+			if got != tt.want || !errors.Is(dec.Error(), tt.err) {
+				t.Fatalf("StringPadded(5, %v) = %q, %v, want %q, %v", tt.pad, got, dec.Error(), tt.want, tt.err)
+			}
+			if dec.Pos() != 5 {
+				t.Fatalf("Pos() = %d, want 5", dec.Pos())
+			}
+			var decErr *DecodeError
+			if tt.err != nil && (!errors.As(dec.Error(), &decErr) || decErr.Offset != 0 || decErr.Size != 5 || decErr.Field != "name") {
+				t.Fatalf("error %#v, want offset 0, size 5, field name", decErr)
+			}
+		})
+	}
+
+	// a zero terminator is a whole code unit, so the zero high byte of 'a' does not end the string
+	dec := NewBytesDecoder([]byte{'a', 0, 0, 0, 'x', 0}, binary.LittleEndian)
+	if got := dec.WithCharset(UTF16LE).StringPadded(3, PadZeroStrict); got != "a" || !errors.Is(dec.Error(), ErrBadPadding) {
+		t.Fatalf("UTF-16 StringPadded = %q, %v", got, dec.Error())
+	}
+}
+
+func TestEncoderStringPadded(t *testing.T) {
+	enc := NewBytesEncoder(binary.LittleEndian)
+	enc.StringPadded("ab", 4, PadZero)
+	enc.StringPadded("ab", 4, PadSpace)
+	enc.WithCharset(UTF16LE).StringPadded("a", 3, PadSpace)
+	want := []byte{'a', 'b', 0, 0, 'a', 'b', ' ', ' ', 'a', 0, ' ', 0, ' ', 0}
+	if got := enc.Encoded(); enc.Error() != nil || !bytes.Equal(got, want) {
+		t.Fatalf("got % x, %v, want % x", got, enc.Error(), want)
+	}
+
+	rejects := []struct {
+		s   string
+		pad Padding
+	}{
+		{"abcde", PadZero},
+		{"a\x00b", PadZeroStrict},
+		{"ab ", PadSpace},
+	}
+	for _, tt := range rejects {
+		enc := NewBytesEncoder(binary.LittleEndian)
+		enc.StringPadded(tt.s, 4, tt.pad)
+		enc.Uint8(0xff)
+		if !errors.Is(enc.Error(), ErrTruncated) || enc.Len() != 5 {
+			t.Fatalf("StringPadded(%q, 4, %v): %v, Len() = %d, want ErrTruncated and 5", tt.s, tt.pad, enc.Error(), enc.Len())
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package encdec
+
+import (
+	"fmt"
+	"io"
+)
+
+// Placeholder is a fixed-size unsigned integer reserved by an Encoder to be filled in later,
+// such as a length or offset field that precedes the data it describes.
+//
+// When the Encoder writes to an io.WriteSeeker, including one returned by NewBytesEncoder, Set seeks back and patches the value in place.
+// Otherwise everything written from the first unset placeholder onward is held in memory,
+// and written out once every placeholder has been set.
+// Held data is never written out if a placeholder is left unset, including after a failed Set;
+// Flush and Encoded then record a failure, reported by Error as usual.
+type Placeholder struct {
+	e      *Encoder
+	op     string
+	label  string
+	pos    int64
+	region int
+	size   int
+	held   bool
+	isSet  bool
+	// holdCount is the holdCount of the Encoder when the placeholder was reserved in held data
+	holdCount int
+	// cipher is the Cipher of the region the placeholder was reserved in, whose offsets start cipherOff bytes before it
+	cipher    Cipher
+	cipherOff int64
+}
+
+// ReserveUint8 reserves a uint8 to be set later.
+func (e *Encoder) ReserveUint8() *Placeholder {
+	return e.reserve("ReserveUint8", 1)
+}
+
+// ReserveUint16 reserves a uint16 to be set later.
+func (e *Encoder) ReserveUint16() *Placeholder {
+	return e.reserve("ReserveUint16", 2)
+}
+
+// ReserveUint32 reserves a uint32 to be set later.
+func (e *Encoder) ReserveUint32() *Placeholder {
+	return e.reserve("ReserveUint32", 4)
+}
+
+// ReserveUint64 reserves a uint64 to be set later.
+func (e *Encoder) ReserveUint64() *Placeholder {
+	return e.reserve("ReserveUint64", 8)
+}
+
+// reserve writes size zero bytes and returns a placeholder for them.
+func (e *Encoder) reserve(op string, size int) *Placeholder {
+	c := e.begin(op)
+	e.flushBits(c)
+	p := &Placeholder{
+		e:      e,
+		op:     op,
+		label:  c.label,
+		pos:    e.Pos(),
+		region: c.region,
+		size:   size,
+	}
+	if e.cipher != nil {
+		p.cipher = e.cipher
+		p.cipherOff = p.pos - e.cipherStart
+	}
+	if !e.canSeek {
+		if e.unresolved == 0 {
+			e.holdPos = p.pos
+		}
+		e.unresolved++
+		p.held = true
+		p.holdCount = e.holdCount
+	}
+	b := e.fixed(c, size)
+	for i := range b {
+		b[i] = 0
+	}
+	e.write(c, b)
+	traceWrite(e, c, uint64(0))
+	return p
+}
+
+// Pos returns the position of the placeholder.
+func (p *Placeholder) Pos() int64 {
+	return p.pos
+}
+
+// Set fills the placeholder with v, failing if v does not fit in the reserved size.
+func (p *Placeholder) Set(v uint64) {
+	e := p.e
+	c := call{op: p.op, label: p.label, pos: p.pos, region: p.region}
+	e.trace.pending.Reset()
+	if p.size < 8 && v>>(8*uint(p.size)) != 0 {
+		e.fail(c, p.pos, p.size, fmt.Errorf("value %d overflows %d byte placeholder", v, p.size))
+		return
+	}
+	b := make([]byte, p.size)
+	switch p.size {
+	case 1:
+		b[0] = byte(v)
+	case 2:
+		e.order.PutUint16(b, uint16(v))
+	case 4:
+		e.order.PutUint32(b, uint32(v))
+	case 8:
+		e.order.PutUint64(b, v)
+	}
+	if e.isDebugMode {
+		e.trace.pending.Write(b)
+	}
+	if p.cipher != nil {
+		p.cipher.Encode(b, p.cipherOff)
+	}
+	if p.held {
+		e.patchHeld(c, p, b)
+	} else {
+		e.patchSeek(c, p, b)
+	}
+	traceWrite(e, c, v)
+}
+
+// SetToCurrentPos fills the placeholder with the current position of the Encoder.
+func (p *Placeholder) SetToCurrentPos() {
+	p.Set(uint64(p.e.Pos()))
+}
+
+// patchHeld overwrites a placeholder in the held data, writing it out once every placeholder is set.
+func (e *Encoder) patchHeld(c call, p *Placeholder, b []byte) {
+	if p.holdCount != e.holdCount {
+		e.fail(c, p.pos, p.size, fmt.Errorf("placeholder already written out"))
+		return
+	}
+	copy(e.hold.Bytes()[p.pos-e.holdPos:], b)
+	if p.isSet {
+		return
+	}
+	p.isSet = true
+	e.unresolved--
+	if e.unresolved > 0 {
+		return
+	}
+	_, err := e.sink().Write(e.hold.Bytes())
+	if err != nil {
+		e.fail(c, e.holdPos, e.hold.Len(), err)
+	}
+	e.hold.Reset()
+	e.holdCount++
+}
+
+// patchSeek overwrites a placeholder in place, returning to the current position afterwards.
+// A buffered Encoder is flushed first, so the patch lands on data already written out.
+func (e *Encoder) patchSeek(c call, p *Placeholder, b []byte) {
+	ws := e.w.(io.WriteSeeker)
+	var err error
+	if e.bw != nil {
+		err = e.bw.Flush()
+	}
+	cur := e.pos
+	if err == nil {
+		_, err = ws.Seek(p.pos, io.SeekStart)
+	}
+	if err == nil {
+		_, err = ws.Write(b)
+	}
+	if err == nil {
+		_, err = ws.Seek(cur, io.SeekStart)
+	}
+	if err != nil {
+		e.fail(c, p.pos, p.size, err)
+	}
+	p.isSet = true
+}
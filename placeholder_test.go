@@ -0,0 +1,106 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePlaceholderExample(enc *Encoder) {
+	size := enc.ReserveUint32()
+	offset := enc.ReserveUint16()
+	start := enc.Pos()
+	enc.StringZero("payload")
+	size.Set(uint64(enc.Pos() - start))
+	offset.SetToCurrentPos()
+	enc.Uint8(0xff)
+}
+
+func TestPlaceholder(t *testing.T) {
+	want := []byte{
+		0x08, 0x00, 0x00, 0x00, // size
+		0x0e, 0x00, // offset
+		'p', 'a', 'y', 'l', 'o', 'a', 'd', 0x00,
+		0xff,
+	}
+
+	w := bytes.NewBuffer(nil)
+	enc := NewEncoder(w, binary.LittleEndian)
+	enc.SetDebugMode(true)
+	writePlaceholderExample(enc)
+	if enc.Error() != nil {
+		t.Fatalf("encode: %v", enc.Error())
+	}
+	if !bytes.Equal(w.Bytes(), want) {
+		t.Fatalf("buffered writer got % x, want % x", w.Bytes(), want)
+	}
+	entries := enc.Trace()
+	last := entries[len(entries)-2]
+	if last.Op != "ReserveUint16" || last.Offset != 4 || !bytes.Equal(last.Raw, []byte{0x0e, 0x00}) {
+		t.Fatalf("unexpected trace entry for offset.SetToCurrentPos(): %+v", last)
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "placeholder.bin"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer f.Close()
+	enc = NewEncoder(f, binary.LittleEndian)
+	writePlaceholderExample(enc)
+	if enc.Error() != nil {
+		t.Fatalf("encode: %v", enc.Error())
+	}
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("seeking writer got % x, want % x", got, want)
+	}
+
+	w.Reset()
+	enc = NewEncoder(w, binary.LittleEndian)
+	count := enc.Field("count").ReserveUint8()
+	enc.Uint8(1)
+	if w.Len() != 0 {
+		t.Fatalf("wrote %d bytes before placeholder was set", w.Len())
+	}
+	count.Set(256)
+	var encErr *EncodeError
+	if !errors.As(enc.Error(), &encErr) || encErr.Field != "count" || encErr.Offset != 0 {
+		t.Fatalf("Error() = %v, want count overflow", enc.Error())
+	}
+	// the placeholder is still unset, so its data is never written and Flush reports it
+	err = enc.Flush()
+	if !errors.As(err, &encErr) || encErr.Op != "Flush" || encErr.Size != 2 || w.Len() != 0 {
+		t.Fatalf("Flush() = %v with %d bytes written, want 2 bytes held", err, w.Len())
+	}
+}
+
+func TestPlaceholderSetAgain(t *testing.T) {
+	w := bytes.NewBuffer(nil)
+	enc := NewEncoder(w, binary.LittleEndian)
+	a := enc.ReserveUint16()
+	b := enc.ReserveUint16()
+	a.Set(4)
+	a.Set(5)
+	b.Set(6)
+	if enc.Error() != nil || !bytes.Equal(w.Bytes(), []byte{5, 0, 6, 0}) {
+		t.Fatalf("set again while held got % x, %v", w.Bytes(), enc.Error())
+	}
+
+	enc.Uint32(1)
+	c := enc.ReserveUint16()
+	a.Set(7)
+	var encErr *EncodeError
+	if !errors.As(enc.Error(), &encErr) || encErr.Offset != 0 || encErr.Op != "ReserveUint16" {
+		t.Fatalf("Error() = %v, want placeholder already written out", enc.Error())
+	}
+	c.Set(8)
+	if !bytes.Equal(w.Bytes(), []byte{5, 0, 6, 0, 1, 0, 0, 0, 8, 0}) {
+		t.Fatalf("got % x after setting a written out placeholder", w.Bytes())
+	}
+}
@@ -0,0 +1,153 @@
+package encdec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrSectionOverrun is the cause recorded when a read goes past the end of a section returned by Sub.
+var ErrSectionOverrun = errors.New("read past end of section")
+
+// ErrSectionUnderrun is the cause recorded when a section returned by Sub is closed before being read to the end.
+var ErrSectionUnderrun = errors.New("section not fully read")
+
+// Sub returns a Decoder restricted to the next n bytes, such as a length-delimited chunk.
+// Positions of the returned Decoder are relative to the start of the section, while
+// errors and trace entries report offsets in the parent stream.
+//
+// The parent must not be used until Close is called on the returned Decoder. Close advances
+// the parent past the section and records on the parent the first error of the section, or
+// ErrSectionUnderrun if the section was not read to the end.
+func (d *Decoder) Sub(n int64) *Decoder {
+	c := d.begin("Sub")
+	start := d.Pos()
+	remaining := d.Remaining()
+	if n < 0 {
+		d.fail(c, start, 0, fmt.Errorf("negative section size %d", n))
+		n = 0
+	}
+	if remaining >= 0 && n > remaining {
+		d.fail(c, start, int(n), io.ErrUnexpectedEOF)
+	}
+	d.bitLeft = 0
+	return d.child(c, &section{r: d.r, base: start, n: n}, d.absPos(start), n, start+n)
+}
+
+// child returns a Decoder reading the n bytes of r with the settings of d, for the call c.
+// Its positions are offset by base in errors and trace entries, and Close moves d to end.
+func (d *Decoder) child(c call, r io.ReadSeeker, base, n, end int64) *Decoder {
+	sub := &Decoder{
+		order:       d.order,
+		r:           r,
+		isDebugMode: d.isDebugMode,
+		isZeroCopy:  d.isZeroCopy,
+		bitOrder:    d.bitOrder,
+		charset:     d.charset,
+		limits:      d.limits,
+		total:       d.total,
+		parent:      d,
+		base:        base,
+		subCall:     c,
+		subEnd:      end,
+		subSize:     n,
+	}
+	sub.trace.region, sub.trace.regions = d.trace.region, d.trace.regions
+	if c.label != "" {
+		sub.path.scopes = []string{c.label}
+	}
+	return sub
+}
+
+// Close finishes a section returned by Sub, advancing the parent past it.
+// It returns the first error of the section, including ErrSectionUnderrun if it was not read to the end.
+// Transforms still pushed onto the section are popped first.
+// For a Decoder returned by OpenFile, Close releases the file instead.
+// Close does nothing for other Decoders, or if called again.
+func (d *Decoder) Close() error {
+	if d.closed {
+		return nil
+	}
+	if d.parent == nil {
+		if d.closer == nil {
+			return nil
+		}
+		d.closed = true
+		d.r = closedStream{}
+		return d.closer()
+	}
+	for len(d.transforms) > 0 {
+		d.PopTransform()
+	}
+	d.closed = true
+	p := d.parent
+	c := d.subCall
+	if d.firstError == nil {
+		pos := d.Pos()
+		if pos >= 0 && pos < d.subSize {
+			d.fail(c, pos, int(d.subSize), fmt.Errorf("%w: %d of %d bytes unread", ErrSectionUnderrun, d.subSize-pos, d.subSize))
+		}
+	}
+	if d.firstError != nil {
+		p.lastError = d.firstError
+		if p.firstError == nil {
+			p.firstError = p.lastError
+		}
+	}
+	p.total = d.total
+	p.trace.regions = d.trace.regions
+	if p.isDebugMode {
+		p.trace.entries = append(p.trace.entries, d.trace.entries...)
+		p.trace.buf.Write(d.trace.buf.Bytes())
+	}
+	p.seek(c, d.subEnd, io.SeekStart)
+	return d.firstError
+}
+
+// section is an io.ReadSeeker restricted to n bytes of r starting at base.
+type section struct {
+	r    io.ReadSeeker
+	base int64
+	off  int64
+	n    int64
+}
+
+// Read reads from the section, returning ErrSectionOverrun once the end is reached.
+func (s *section) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if s.off >= s.n {
+		return 0, ErrSectionOverrun
+	}
+	if int64(len(p)) > s.n-s.off {
+		p = p[:s.n-s.off]
+	}
+	n, err := s.r.Read(p)
+	s.off += int64(n)
+	return n, err
+}
+
+// Seek moves within the section.
+func (s *section) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = s.off + offset
+	case io.SeekEnd:
+		abs = s.n + offset
+	default:
+		return s.off, fmt.Errorf("invalid whence %d", whence)
+	}
+	if abs < 0 {
+		return s.off, fmt.Errorf("negative position %d", abs)
+	}
+	_, err := s.r.Seek(s.base+abs, io.SeekStart)
+	if err != nil {
+		return s.off, err
+	}
+	s.off = abs
+	return abs, nil
+}
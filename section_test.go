@@ -0,0 +1,65 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestSub(t *testing.T) {
+	data := []byte{
+		'f', 'm', 't', ' ', 0x06, 0x00, 0x00, 0x00, // chunk header
+		0x01, 0x00, 0x02, 0x00, 0x03, 0x00, // chunk body
+		'd', 'a', 't', 'a', 0x02, 0x00, 0x00, 0x00, // chunk header
+		0xaa, 0xbb, // chunk body
+	}
+
+	dec := NewDecoder(bytes.NewReader(data), binary.LittleEndian)
+	dec.SetDebugMode(true)
+	id := dec.StringFixed(4)
+	size := dec.Uint32()
+	chunk := dec.Field(id).Sub(int64(size))
+	chunk.Uint16()
+	chunk.Uint16()
+	if chunk.Pos() != 4 || chunk.Remaining() != 2 {
+		t.Fatalf("Pos() = %d, Remaining() = %d, want 4 and 2", chunk.Pos(), chunk.Remaining())
+	}
+	chunk.Uint16()
+	if err := chunk.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if dec.Pos() != 14 {
+		t.Fatalf("parent Pos() = %d after Close, want 14", dec.Pos())
+	}
+
+	// under-read, the parent still ends up past the section
+	id = dec.StringFixed(4)
+	size = dec.Uint32()
+	chunk = dec.Field(id).Sub(int64(size))
+	chunk.Uint8()
+	err := chunk.Close()
+	var decErr *DecodeError
+	if !errors.Is(err, ErrSectionUnderrun) || !errors.As(dec.Error(), &decErr) || decErr.Field != "data" || decErr.Offset != 23 {
+		t.Fatalf("Close() = %v, parent Error() = %v, want data underrun at 23", err, dec.Error())
+	}
+	if dec.Pos() != 24 {
+		t.Fatalf("parent Pos() = %d after Close, want 24", dec.Pos())
+	}
+	if entries := dec.Trace(); len(entries) != 8 || entries[4].Offset != 12 || entries[4].Label != "fmt " {
+		t.Fatalf("unexpected parent trace: %+v", entries)
+	}
+
+	// over-read
+	dec = NewDecoder(bytes.NewReader(data), binary.LittleEndian)
+	dec.Skip(8)
+	chunk = dec.Sub(6)
+	chunk.Field("values").Bytes(8)
+	err = chunk.Close()
+	if !errors.Is(err, ErrSectionOverrun) || !errors.As(err, &decErr) || decErr.Field != "values" || decErr.Offset != 8 {
+		t.Fatalf("Close() = %v, want values overrun at 8", err)
+	}
+	if dec.Pos() != 14 {
+		t.Fatalf("parent Pos() = %d after Close, want 14", dec.Pos())
+	}
+}
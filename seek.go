@@ -0,0 +1,128 @@
+package encdec
+
+import (
+	"fmt"
+	"io"
+)
+
+// Seek moves to offset relative to whence (io.SeekStart, io.SeekCurrent or io.SeekEnd) and returns the new position,
+// implementing io.Seeker. A failure is also recorded like any other decode error.
+// It discards the rest of a partially consumed byte.
+func (d *Decoder) Seek(offset int64, whence int) (int64, error) {
+	c := d.begin("Seek")
+	return d.seek(c, offset, whence)
+}
+
+// Skip moves forward n bytes without reading them, failing if that passes the end of the stream.
+func (d *Decoder) Skip(n int) {
+	c := d.begin("Skip")
+	d.skip(c, int64(n))
+}
+
+// AlignTo skips forward to the next position that is a multiple of boundary.
+func (d *Decoder) AlignTo(boundary int) {
+	c := d.begin("AlignTo")
+	if boundary <= 0 {
+		d.fail(c, d.Pos(), 0, fmt.Errorf("invalid boundary %d", boundary))
+		return
+	}
+	pos := d.Pos()
+	if pos < 0 {
+		d.fail(c, pos, 0, fmt.Errorf("unknown position"))
+		return
+	}
+	pad := (int64(boundary) - pos%int64(boundary)) % int64(boundary)
+	d.skip(c, pad)
+}
+
+// Peek returns the next n bytes without advancing.
+func (d *Decoder) Peek(n int) []byte {
+	c := d.begin("Peek")
+	if canPeek(d.r) {
+		return d.peek(c, n)
+	}
+	pos := d.Pos()
+	total := d.total
+	b := d.bytes(c, n)
+	d.seek(c, pos, io.SeekStart)
+	// the bytes count toward Limits.MaxTotal when they are read
+	d.total = total
+	return b
+}
+
+// Len returns the total length of the stream, or -1 if it cannot be determined.
+func (d *Decoder) Len() int64 {
+	return streamLen(d.r)
+}
+
+// sizer is implemented by streams that know their length without seeking to the end,
+// which would discard a stream that only moves forward.
+type sizer interface {
+	// size returns the length of the stream, or false if it cannot be determined.
+	size() (int64, bool)
+}
+
+// streamLen returns the length of r, or -1 if it cannot be determined.
+func streamLen(r io.ReadSeeker) int64 {
+	if s, ok := r.(sizer); ok {
+		n, ok := s.size()
+		if !ok {
+			return -1
+		}
+		return n
+	}
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	_, err = r.Seek(pos, io.SeekStart)
+	if err != nil {
+		return -1
+	}
+	return end
+}
+
+// Remaining returns the number of bytes left in the stream, or -1 if it cannot be determined.
+func (d *Decoder) Remaining() int64 {
+	size := d.Len()
+	pos := d.Pos()
+	if size < 0 || pos < 0 {
+		return -1
+	}
+	if pos > size {
+		return 0
+	}
+	return size - pos
+}
+
+// seek moves the underlying stream, recording any failure.
+func (d *Decoder) seek(c call, offset int64, whence int) (int64, error) {
+	d.bitLeft = 0
+	start := d.Pos()
+	pos, err := d.r.Seek(offset, whence)
+	if err != nil {
+		d.fail(c, start, 0, err)
+		return start, d.lastError
+	}
+	return pos, nil
+}
+
+// skip moves forward n bytes, failing with io.ErrUnexpectedEOF if that passes the end of the stream.
+func (d *Decoder) skip(c call, n int64) {
+	if n < 0 {
+		d.fail(c, d.Pos(), int(n), fmt.Errorf("negative skip %d", n))
+		return
+	}
+	remaining := d.Remaining()
+	if remaining >= 0 && n > remaining {
+		pos := d.Pos()
+		d.seek(c, 0, io.SeekEnd)
+		d.fail(c, pos, int(n), io.ErrUnexpectedEOF)
+		return
+	}
+	d.seek(c, n, io.SeekCurrent)
+}
@@ -0,0 +1,57 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestSeek(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader([]byte{
+		0x01,             // flag
+		0x00, 0x00, 0x00, // padding
+		0x08, 0x00, 0x00, 0x00, // offset of value
+		0x2a, 0x00, // value
+	}), binary.LittleEndian)
+	dec.SetDebugMode(true)
+
+	if dec.Len() != 10 || dec.Remaining() != 10 {
+		t.Fatalf("Len() = %d, Remaining() = %d, want 10 and 10", dec.Len(), dec.Remaining())
+	}
+	if b := dec.Peek(1); b[0] != 0x01 || dec.Pos() != 0 {
+		t.Fatalf("Peek(1) = % x at pos %d, want 01 at pos 0", b, dec.Pos())
+	}
+	dec.Uint8()
+	dec.AlignTo(4)
+	if dec.Pos() != 4 {
+		t.Fatalf("AlignTo(4) moved to %d, want 4", dec.Pos())
+	}
+	offset := dec.Uint32()
+	dec.Seek(int64(offset), io.SeekStart)
+	if v := dec.Uint16(); v != 42 {
+		t.Fatalf("Uint16() = %d, want 42", v)
+	}
+	if dec.Remaining() != 0 {
+		t.Fatalf("Remaining() = %d, want 0", dec.Remaining())
+	}
+	if pos, err := dec.Seek(-2, io.SeekEnd); pos != 8 || err != nil {
+		t.Fatalf("Seek(-2, io.SeekEnd) = %d, %v, want 8", pos, err)
+	}
+	if dec.Error() != nil {
+		t.Fatalf("decode: %v", dec.Error())
+	}
+	if len(dec.Trace()) != 3 {
+		t.Fatalf("Trace() = %+v, want only the 3 reads", dec.Trace())
+	}
+
+	dec.Field("padding").Skip(4)
+	var decErr *DecodeError
+	if !errors.As(dec.Error(), &decErr) || decErr.Field != "padding" || !errors.Is(decErr, io.ErrUnexpectedEOF) {
+		t.Fatalf("Error() = %v, want padding unexpected EOF", dec.Error())
+	}
+	if dec.Pos() != 10 {
+		t.Fatalf("Pos() = %d after failed Skip, want 10", dec.Pos())
+	}
+}
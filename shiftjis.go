@@ -0,0 +1,130 @@
+package encdec
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ShiftJIS is Shift-JIS as written by Windows, code page 932, with its NEC and IBM extensions.
+// Its code units are bytes, so lengths count bytes and strings end with a single zero byte.
+var ShiftJIS Charset = shiftJISCharset{}
+
+// shiftJISEncode maps runes to their double-byte codes, built from shiftJISTable on first use.
+// Where several codes map to one rune, the one Windows writes is kept, see shiftJISRank.
+var shiftJISEncode struct {
+	once  sync.Once
+	codes map[rune]uint16
+}
+
+type shiftJISCharset struct{}
+
+// isShiftJISLead reports whether c starts a double-byte character.
+func isShiftJISLead(c byte) bool {
+	return (c >= 0x81 && c <= 0x9f) || (c >= 0xe0 && c <= 0xfc)
+}
+
+func (shiftJISCharset) Decode(b []byte) (string, error) {
+	var sb strings.Builder
+	sb.Grow(len(b))
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c <= 0x80:
+			sb.WriteRune(rune(c))
+		case c == 0xa0:
+			sb.WriteRune(0xf8f0)
+		case c >= 0xa1 && c <= 0xdf:
+			sb.WriteRune(0xff61 + rune(c-0xa1))
+		case c >= 0xfd:
+			sb.WriteRune(0xf8f1 + rune(c-0xfd))
+		case i+1 == len(b):
+			sb.WriteRune(utf8.RuneError)
+		default:
+			r := shiftJISRune(c, b[i+1])
+			if r != 0 || b[i+1] >= 0x80 {
+				// an unassigned pair is replaced as a whole unless its trail byte is ASCII,
+				// which is kept for the next character
+				i++
+			}
+			if r == 0 {
+				r = utf8.RuneError
+			}
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String(), nil
+}
+
+// shiftJISRune returns the rune of the double-byte character lead, trail, or 0 if it is unassigned.
+func shiftJISRune(lead, trail byte) rune {
+	if trail < 0x40 || trail > 0xfc {
+		return 0
+	}
+	row := int(lead - 0x81)
+	if lead >= 0xe0 {
+		row = int(lead-0xe0) + 0x9f - 0x81 + 1
+	}
+	return rune(shiftJISTable[row*(0xfc-0x40+1)+int(trail-0x40)])
+}
+
+// shiftJISRank orders the codes sharing a rune by the preference of Windows when encoding, lowest first:
+// JIS X 0208, then NEC row 13 (lead 0x87), then the IBM extensions (0xFA-0xFC),
+// then the NEC-selected IBM extensions (0xED-0xEE).
+func shiftJISRank(lead byte) int {
+	switch {
+	case lead == 0x87:
+		return 1
+	case lead >= 0xfa:
+		return 2
+	case lead == 0xed || lead == 0xee:
+		return 3
+	}
+	return 0
+}
+
+func (shiftJISCharset) Encode(s string) ([]byte, error) {
+	shiftJISEncode.once.Do(func() {
+		shiftJISEncode.codes = make(map[rune]uint16)
+		for lead := 0x81; lead <= 0xfc; lead++ {
+			if !isShiftJISLead(byte(lead)) {
+				continue
+			}
+			for trail := 0x40; trail <= 0xfc; trail++ {
+				r := shiftJISRune(byte(lead), byte(trail))
+				if r == 0 {
+					continue
+				}
+				code, ok := shiftJISEncode.codes[r]
+				if !ok || shiftJISRank(byte(lead)) < shiftJISRank(byte(code>>8)) {
+					shiftJISEncode.codes[r] = uint16(lead<<8 | trail)
+				}
+			}
+		}
+	})
+
+	var err error
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r <= 0x80:
+			b = append(b, byte(r))
+		case r == 0xf8f0:
+			b = append(b, 0xa0)
+		case r >= 0xff61 && r <= 0xff9f:
+			b = append(b, byte(r-0xff61+0xa1))
+		case r >= 0xf8f1 && r <= 0xf8f3:
+			b = append(b, byte(r-0xf8f1+0xfd))
+		default:
+			code, ok := shiftJISEncode.codes[r]
+			if !ok {
+				b, err = append(b, '?'), ErrUnmappable
+				continue
+			}
+			b = append(b, byte(code>>8), byte(code))
+		}
+	}
+	return b, err
+}
+
+func (shiftJISCharset) UnitSize() int { return 1 }
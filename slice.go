@@ -0,0 +1,194 @@
+package encdec
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// SliceN decodes n elements with fn, e.g. encdec.SliceN(dec, 4, (*encdec.Decoder).Uint16).
+// Elements are labeled by index under any pending field label, and decoding stops at the first error.
+func SliceN[T any](d *Decoder, n int, fn func(*Decoder) T) []T {
+	name := d.path.next
+	d.path.next = ""
+	return decodeSlice(d, "SliceN", name, n, fn)
+}
+
+// SliceU8 decodes a uint8 count followed by that many elements with fn.
+func SliceU8[T any](d *Decoder, fn func(*Decoder) T) []T {
+	name := d.path.next
+	n := int(d.Uint8())
+	return decodeSlice(d, "SliceU8", name, n, fn)
+}
+
+// SliceU16 decodes a uint16 count followed by that many elements with fn.
+func SliceU16[T any](d *Decoder, fn func(*Decoder) T) []T {
+	name := d.path.next
+	n := int(d.Uint16())
+	return decodeSlice(d, "SliceU16", name, n, fn)
+}
+
+// SliceU32 decodes a uint32 count followed by that many elements with fn,
+// like the someSubStructs loop in the examples.
+func SliceU32[T any](d *Decoder, fn func(*Decoder) T) []T {
+	name := d.path.next
+	n := d.count("SliceU32", name, uint64(d.Uint32()))
+	return decodeSlice(d, "SliceU32", name, n, fn)
+}
+
+// SliceUvarint decodes a uvarint count followed by that many elements with fn.
+func SliceUvarint[T any](d *Decoder, fn func(*Decoder) T) []T {
+	name := d.path.next
+	n := d.count("SliceUvarint", name, d.Uvarint())
+	return decodeSlice(d, "SliceUvarint", name, n, fn)
+}
+
+// SliceUntilEnd decodes elements with fn until the end of the stream, typically a section returned by Sub.
+// It fails if the length of the stream cannot be determined or an element reads nothing.
+func SliceUntilEnd[T any](d *Decoder, fn func(*Decoder) T) []T {
+	name := d.path.next
+	d.path.next = ""
+	c := call{op: "SliceUntilEnd", label: d.path.join(name)}
+	if d.Remaining() < 0 {
+		d.fail(c, d.Pos(), 0, fmt.Errorf("unknown stream length"))
+		return nil
+	}
+	if name != "" {
+		d.path.push(name)
+		defer d.path.pop()
+	}
+	s := []T{}
+	lastError := d.lastError
+	for i := 0; d.Remaining() > 0 && d.lastError == lastError; i++ {
+		pos := d.Pos()
+		d.path.push(indexLabel(i))
+		s = append(s, fn(d))
+		d.path.pop()
+		if d.lastError == lastError && d.Pos() == pos {
+			d.fail(call{op: c.op, label: d.path.join(indexLabel(i))}, pos, 0, fmt.Errorf("element read no data"))
+		}
+	}
+	return s
+}
+
+// count converts a decoded count to an int, recording a failure under name if it overflows.
+func (d *Decoder) count(op string, name string, n uint64) int {
+	if n > uint64(maxInt) {
+		d.fail(call{op: op, label: d.path.join(name)}, d.Pos(), 0, fmt.Errorf("count %d overflows int", n))
+		return 0
+	}
+	return int(n)
+}
+
+// decodeSlice decodes n elements with fn under the scope name.
+// The count is checked against Limits.MaxAlloc using the in-memory size of T, and only small slices are
+// allocated up front, so a corrupt count fails on the data rather than on a huge allocation.
+func decodeSlice[T any](d *Decoder, op string, name string, n int, fn func(*Decoder) T) []T {
+	c := call{op: op, label: d.path.join(name)}
+	size := int(unsafe.Sizeof(*new(T)))
+	if n < 0 {
+		d.fail(c, d.Pos(), 0, fmt.Errorf("negative count %d", n))
+		return nil
+	}
+	if d.limits.MaxAlloc > 0 && size > 0 && n > d.limits.MaxAlloc/size {
+		d.fail(c, d.Pos(), 0, fmt.Errorf("%w: %d elements of %d bytes exceeds max allocation of %d", ErrLimitExceeded, n, size, d.limits.MaxAlloc))
+		return nil
+	}
+	if name != "" {
+		d.path.push(name)
+		defer d.path.pop()
+	}
+	capacity := n
+	if size > 0 && capacity > sanityCheckSize/size {
+		capacity = sanityCheckSize / size
+	}
+	s := make([]T, 0, capacity)
+	lastError := d.lastError
+	for i := 0; i < n && d.lastError == lastError; i++ {
+		d.path.push(indexLabel(i))
+		s = append(s, fn(d))
+		d.path.pop()
+	}
+	return s
+}
+
+// PutSlice encodes each element of s with fn, the counterpart of SliceUntilEnd.
+func PutSlice[T any](e *Encoder, s []T, fn func(*Encoder, T)) {
+	name := e.path.next
+	e.path.next = ""
+	encodeSlice(e, name, s, fn)
+}
+
+// PutSliceN encodes each element of s with fn, failing if s does not have exactly n elements, the counterpart of SliceN.
+func PutSliceN[T any](e *Encoder, s []T, n int, fn func(*Encoder, T)) {
+	name := e.path.next
+	if len(s) != n {
+		c := e.begin("PutSliceN")
+		e.fail(c, e.Pos(), 0, fmt.Errorf("length %d does not match count %d", len(s), n))
+		return
+	}
+	e.path.next = ""
+	encodeSlice(e, name, s, fn)
+}
+
+// PutSliceU8 encodes the length of s as a uint8 followed by each element with fn, the counterpart of SliceU8.
+func PutSliceU8[T any](e *Encoder, s []T, fn func(*Encoder, T)) {
+	name := e.path.next
+	if !e.checkCount("PutSliceU8", len(s), 0xff) {
+		return
+	}
+	e.Uint8(uint8(len(s)))
+	encodeSlice(e, name, s, fn)
+}
+
+// PutSliceU16 encodes the length of s as a uint16 followed by each element with fn, the counterpart of SliceU16.
+func PutSliceU16[T any](e *Encoder, s []T, fn func(*Encoder, T)) {
+	name := e.path.next
+	if !e.checkCount("PutSliceU16", len(s), 0xffff) {
+		return
+	}
+	e.Uint16(uint16(len(s)))
+	encodeSlice(e, name, s, fn)
+}
+
+// PutSliceU32 encodes the length of s as a uint32 followed by each element with fn, the counterpart of SliceU32.
+func PutSliceU32[T any](e *Encoder, s []T, fn func(*Encoder, T)) {
+	name := e.path.next
+	if !e.checkCount("PutSliceU32", len(s), 0xffffffff) {
+		return
+	}
+	e.Uint32(uint32(len(s)))
+	encodeSlice(e, name, s, fn)
+}
+
+// PutSliceUvarint encodes the length of s as a uvarint followed by each element with fn, the counterpart of SliceUvarint.
+func PutSliceUvarint[T any](e *Encoder, s []T, fn func(*Encoder, T)) {
+	name := e.path.next
+	e.Uvarint(uint64(len(s)))
+	encodeSlice(e, name, s, fn)
+}
+
+// encodeSlice encodes each element of s with fn under the scope name.
+func encodeSlice[T any](e *Encoder, name string, s []T, fn func(*Encoder, T)) {
+	if name != "" {
+		e.path.push(name)
+		defer e.path.pop()
+	}
+	for i, v := range s {
+		e.path.push(indexLabel(i))
+		fn(e, v)
+		e.path.pop()
+	}
+}
+
+// checkCount records a failure under the pending field label, consuming it, if n does not fit in a count of at most limit.
+func (e *Encoder) checkCount(op string, n int, limit uint64) bool {
+	if uint64(n) <= limit {
+		return true
+	}
+	c := e.begin(op)
+	e.fail(c, e.Pos(), 0, fmt.Errorf("count %d overflows %d", n, limit))
+	return false
+}
+
+// maxInt is the largest value of an int.
+const maxInt = int(^uint(0) >> 1)
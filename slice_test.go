@@ -0,0 +1,95 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSlice(t *testing.T) {
+	data := []byte{
+		0x02, 0x01, 0x00, 0x02, 0x00, // u8 count, uint16s
+		0x01, 0x00, 0x03, 0x00, // u16 count, uint16
+		0x01, 0x00, 0x00, 0x00, 0x04, 0x00, // u32 count, uint16
+		0x02, 0x05, 0x00, 0x06, 0x00, // uvarint count, uint16s
+		0x07, 0x00, 0x08, 0x00, // 2 uint16s
+		0x61, 0x00, 0x62, 0x63, 0x00, // zero terminated strings until end
+	}
+	dec := NewDecoder(bytes.NewReader(data), binary.LittleEndian)
+	u16 := (*Decoder).Uint16
+	got := [][]uint16{
+		SliceU8(dec, u16),
+		SliceU16(dec, u16),
+		SliceU32(dec, u16),
+		SliceUvarint(dec, u16),
+		SliceN(dec, 2, u16),
+	}
+	strs := SliceUntilEnd(dec, (*Decoder).StringZero)
+	if dec.Error() != nil {
+		t.Fatalf("decode: %v", dec.Error())
+	}
+	want := [][]uint16{{1, 2}, {3}, {4}, {5, 6}, {7, 8}}
+	if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(strs, []string{"a", "bc"}) {
+		t.Fatalf("got %v %v, want %v [a bc]", got, strs, want)
+	}
+
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf, binary.LittleEndian)
+	put := (*Encoder).Uint16
+	PutSliceU8(enc, want[0], put)
+	PutSliceU16(enc, want[1], put)
+	PutSliceU32(enc, want[2], put)
+	PutSliceUvarint(enc, want[3], put)
+	PutSliceN(enc, want[4], 2, put)
+	PutSlice(enc, strs, (*Encoder).StringZero)
+	if enc.Error() != nil {
+		t.Fatalf("encode: %v", enc.Error())
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("encoded % x, want % x", buf.Bytes(), data)
+	}
+
+	PutSliceN(enc.Field("fixed"), want[0], 3, put)
+	var encErr *EncodeError
+	if !errors.As(enc.Error(), &encErr) || encErr.Field != "fixed" {
+		t.Fatalf("PutSliceN length mismatch: Error() = %v", enc.Error())
+	}
+}
+
+func TestSliceErrors(t *testing.T) {
+	// truncated element is labeled with its index
+	dec := NewDecoder(bytes.NewReader([]byte{0x03, 0x01, 0x00, 0x02}), binary.LittleEndian)
+	s := SliceU8(dec.Field("ids"), (*Decoder).Uint16)
+	var decErr *DecodeError
+	if !errors.Is(dec.Error(), io.ErrUnexpectedEOF) || !errors.As(dec.Error(), &decErr) || decErr.Field != "ids[1]" || len(s) != 2 {
+		t.Fatalf("Error() = %v with %v, want ids[1] unexpected EOF", dec.Error(), s)
+	}
+
+	// hostile count is refused before allocating
+	dec = NewDecoder(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}), binary.LittleEndian)
+	dec.SetLimits(Limits{MaxAlloc: 1024})
+	s = SliceU32(dec.Field("ids"), (*Decoder).Uint16)
+	if !errors.Is(dec.Error(), ErrLimitExceeded) || !errors.As(dec.Error(), &decErr) || decErr.Field != "ids" || s != nil {
+		t.Fatalf("Error() = %v with %v, want ids limit exceeded", dec.Error(), s)
+	}
+
+	// an element that reads nothing cannot loop forever
+	dec = NewDecoder(bytes.NewReader([]byte{0x01}), binary.LittleEndian)
+	SliceUntilEnd(dec, func(d *Decoder) int { return 0 })
+	if !errors.As(dec.Error(), &decErr) || decErr.Field != "[0]" {
+		t.Fatalf("Error() = %v, want [0] read no data", dec.Error())
+	}
+
+	// a length too large for its prefix writes nothing
+	w := bytes.NewBuffer(nil)
+	enc := NewEncoder(w, binary.LittleEndian)
+	PutSliceU16(enc, make([]byte, 0xffff), (*Encoder).Uint8)
+	PutSliceU8(enc.Field("data"), make([]byte, 300), (*Encoder).Uint8)
+	var encErr *EncodeError
+	if !errors.As(enc.Error(), &encErr) || encErr.Field != "data" || w.Len() != 2+0xffff {
+		t.Fatalf("Error() = %v after writing %d bytes, want data count overflow", enc.Error(), w.Len())
+	}
+}
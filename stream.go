@@ -0,0 +1,133 @@
+package encdec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNotSeekable is the cause recorded when a Decoder returned by NewReaderDecoder is asked to
+// move backwards or relative to the end of its stream.
+var ErrNotSeekable = errors.New("stream is not seekable")
+
+// NewReaderDecoder returns a Decoder reading from a plain io.Reader, such as a network connection, pipe or gzip stream.
+// Reads are buffered and the position is tracked internally, so errors still report offsets.
+//
+// Peek (up to 4096 bytes), Skip, AlignTo, Sub and forward Seek work as usual. Seeking backwards or relative to
+// the end fails with ErrNotSeekable, and Len and Remaining return -1.
+func NewReaderDecoder(r io.Reader, order binary.ByteOrder) *Decoder {
+	return NewDecoder(&stream{r: bufio.NewReader(r)}, order)
+}
+
+// peeker is implemented by streams that can return upcoming bytes without seeking back.
+type peeker interface {
+	peek(n int) ([]byte, error)
+}
+
+// stream adapts a buffered io.Reader to an io.ReadSeeker that can only move forward.
+type stream struct {
+	r   *bufio.Reader
+	pos int64
+}
+
+// Read reads from the stream, advancing the position.
+func (s *stream) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.pos += int64(n)
+	return n, err
+}
+
+// Seek reports the position, or moves forward by discarding bytes.
+func (s *stream) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = s.pos + offset
+	case io.SeekEnd:
+		return s.pos, fmt.Errorf("%w: cannot seek relative to end", ErrNotSeekable)
+	default:
+		return s.pos, fmt.Errorf("invalid whence %d", whence)
+	}
+	if abs < s.pos {
+		return s.pos, fmt.Errorf("%w: cannot seek back from %d to %d", ErrNotSeekable, s.pos, abs)
+	}
+	for abs > s.pos {
+		step := abs - s.pos
+		if step > int64(maxInt) {
+			step = int64(maxInt)
+		}
+		n, err := s.r.Discard(int(step))
+		s.pos += int64(n)
+		if err == io.EOF {
+			return s.pos, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return s.pos, err
+		}
+	}
+	return s.pos, nil
+}
+
+// size reports that the length of the stream is unknown.
+func (s *stream) size() (int64, bool) {
+	return 0, false
+}
+
+// peek returns the next n bytes without consuming them.
+func (s *stream) peek(n int) ([]byte, error) {
+	b, err := s.r.Peek(n)
+	if err == io.EOF && len(b) < n {
+		err = io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+// size returns the length of the section.
+func (s *section) size() (int64, bool) {
+	return s.n, true
+}
+
+// peek returns the next n bytes of the section without consuming them.
+// It must only be used if canPeek reports true for the section.
+func (s *section) peek(n int) ([]byte, error) {
+	p := s.r.(peeker)
+	if int64(n) > s.n-s.off {
+		b, _ := p.peek(int(s.n - s.off))
+		return b, ErrSectionOverrun
+	}
+	return p.peek(n)
+}
+
+// canPeek reports whether r is, or is a section or Cipher region of, a stream that is peeked rather than read and seeked back.
+func canPeek(r io.Reader) bool {
+	switch r := r.(type) {
+	case *stream:
+		return true
+	case *section:
+		return canPeek(r.r)
+	case *cipherStream:
+		return canPeek(r.r)
+	}
+	return false
+}
+
+// peek returns a copy of the next n bytes of a stream that canPeek, recording any failure.
+// Missing bytes are left zero, as with a short read.
+func (d *Decoder) peek(c call, n int) []byte {
+	d.bitLeft = 0
+	pos := d.Pos()
+	if !d.checkAlloc(c, pos, n) {
+		return nil
+	}
+	b, err := d.r.(peeker).peek(n)
+	if err != nil {
+		d.fail(c, pos, n, err)
+	}
+	out := make([]byte, n)
+	copy(out, b)
+	return out
+}
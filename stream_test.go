@@ -0,0 +1,76 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReaderDecoder(t *testing.T) {
+	data := []byte{
+		0x01, 0x00, 0x02, 0x00, // header
+		0xaa, 0xbb, 0xcc, // padding
+		0x04, 0x00, 0x00, 0x00, 0x61, 0x62, 0x63, 0x64, // name
+		0x02, 0x00, 0x00, 0x00, 0x05, 0x06, // section
+		0x07, // tail
+	}
+	dec := NewReaderDecoder(iotest.OneByteReader(bytes.NewReader(data)), binary.LittleEndian)
+	if dec.Uint16() != 1 || dec.Uint16() != 2 {
+		t.Fatalf("header mismatch: %v", dec.Error())
+	}
+	dec.Skip(3)
+	if peek := dec.Peek(4); !bytes.Equal(peek, []byte{0x04, 0x00, 0x00, 0x00}) || dec.Pos() != 7 {
+		t.Fatalf("Peek(4) = % x at %d, want 04 00 00 00 at 7", peek, dec.Pos())
+	}
+	if name := dec.StringLenPrefixUint32(); name != "abcd" {
+		t.Fatalf("name = %q, want abcd", name)
+	}
+	sub := dec.Sub(int64(dec.Uint32()))
+	if sub.Remaining() != 2 {
+		t.Fatalf("section Remaining() = %d, want 2", sub.Remaining())
+	}
+	if peek := sub.Peek(2); !bytes.Equal(peek, []byte{0x05, 0x06}) {
+		t.Fatalf("section Peek(2) = % x", peek)
+	}
+	sub.Uint8()
+	if err := sub.Close(); !errors.Is(err, ErrSectionUnderrun) {
+		t.Fatalf("Close() = %v, want underrun", err)
+	}
+	if dec.Pos() != 21 || dec.Len() != -1 || dec.Remaining() != -1 {
+		t.Fatalf("Pos() = %d, Len() = %d, Remaining() = %d, want 21, -1, -1", dec.Pos(), dec.Len(), dec.Remaining())
+	}
+	if v := dec.Uint8(); v != 7 {
+		t.Fatalf("tail = %d, want 7", v)
+	}
+
+	// failures report offsets tracked by the stream
+	dec.Field("missing").Uint32()
+	var decErr *DecodeError
+	if !errors.Is(dec.LastError(), io.EOF) || !errors.As(dec.LastError(), &decErr) || decErr.Offset != 22 || decErr.Field != "missing" {
+		t.Fatalf("LastError() = %v, want missing EOF at 22", dec.LastError())
+	}
+}
+
+func TestReaderDecoderNotSeekable(t *testing.T) {
+	dec := NewReaderDecoder(bytes.NewBufferString("abcdef"), binary.LittleEndian)
+	dec.Skip(4)
+	pos, err := dec.Seek(1, io.SeekStart)
+	if !errors.Is(err, ErrNotSeekable) || pos != 4 {
+		t.Fatalf("Seek back = %d, %v, want 4 and ErrNotSeekable", pos, err)
+	}
+	_, err = dec.Seek(0, io.SeekEnd)
+	if !errors.Is(err, ErrNotSeekable) {
+		t.Fatalf("Seek end = %v, want ErrNotSeekable", err)
+	}
+	pos, err = dec.Seek(5, io.SeekStart)
+	if err != nil || pos != 5 || dec.StringFixed(1) != "f" {
+		t.Fatalf("Seek forward = %d, %v", pos, err)
+	}
+	dec.Skip(1)
+	if !errors.Is(dec.LastError(), io.ErrUnexpectedEOF) {
+		t.Fatalf("Skip past end: %v, want unexpected EOF", dec.LastError())
+	}
+}
@@ -0,0 +1,497 @@
+package encdec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	"github.com/xackery/encdec/internal/tag"
+)
+
+// Struct decodes the exported fields of the struct v points to, in order, as described by their encdec struct tags.
+// Each field is labeled with its name, so errors and traces read like "Header.Flags" or "Items[2].Name".
+// See the README for the tag format. Invalid tags and unsupported field types are recorded like any other decode error.
+func (d *Decoder) Struct(v interface{}) {
+	label := d.path.take()
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		d.fail(call{op: "Struct", label: label}, d.Pos(), 0, fmt.Errorf("want pointer to struct, got %T", v))
+		return
+	}
+	if label != "" {
+		d.path.push(label)
+		defer d.path.pop()
+	}
+	d.structFields(rv.Elem())
+}
+
+// structFields decodes each exported field of rv.
+// Fields whose type implements Unmarshaler are decoded with Value instead.
+func (d *Decoder) structFields(rv reflect.Value) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		t, err := tag.Parse(sf.Tag.Get("encdec"))
+		if err != nil {
+			d.fail(call{op: "Struct", label: d.path.join(sf.Name)}, d.Pos(), 0, err)
+			return
+		}
+		if t.Skip {
+			continue
+		}
+		if !d.structValue(rv.Field(i), t, sf.Name, rv) {
+			return
+		}
+	}
+}
+
+// structValue decodes a single value named name into rv, looking up len=Field counts in parent.
+// It returns false if decoding should stop.
+func (d *Decoder) structValue(rv reflect.Value, t tag.Tag, name string, parent reflect.Value) bool {
+	if t.Order != "" {
+		order := d.order
+		d.order = tagOrder(t.Order)
+		defer func() { d.order = order }()
+	}
+	if t.Kind == "" && rv.CanAddr() {
+		if u, ok := rv.Addr().Interface().(Unmarshaler); ok {
+			d.Field(name).Value(u)
+			return true
+		}
+	}
+	lastError := d.lastError
+	switch rv.Kind() {
+	case reflect.Struct:
+		d.path.push(name)
+		d.structFields(rv)
+		d.path.pop()
+	case reflect.String:
+		err := t.CheckString()
+		if err != nil {
+			d.fail(call{op: "Struct", label: d.path.join(name)}, d.Pos(), 0, err)
+			return false
+		}
+		switch {
+		case t.Zero:
+			rv.SetString(d.Field(name).StringZero())
+		case t.Prefix == "u8":
+			rv.SetString(d.Field(name).StringLenPrefixUint8())
+		case t.Prefix == "u16":
+			rv.SetString(d.Field(name).StringLenPrefixUint16())
+		case t.Prefix == "u32":
+			rv.SetString(d.Field(name).StringLenPrefixUint32())
+		default:
+			n, ok := d.structCount(t, name, parent)
+			if !ok {
+				return false
+			}
+			rv.SetString(d.Field(name).StringFixed(n))
+		}
+	case reflect.Slice:
+		n, ok := d.structCount(t, name, parent)
+		if !ok {
+			return false
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 && (t.Kind == "" || t.Kind == "bytes" || t.Kind == "u8") {
+			rv.SetBytes(d.Field(name).Bytes(n))
+			break
+		}
+		rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+		elemTag := tag.Tag{Kind: t.Kind, Zero: t.Zero}
+		d.path.push(name)
+		for i := 0; i < n && d.lastError == lastError; i++ {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			d.structValue(elem, elemTag, indexLabel(i), parent)
+			rv.Set(reflect.Append(rv, elem))
+		}
+		d.path.pop()
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 && (t.Kind == "" || t.Kind == "bytes" || t.Kind == "u8") {
+			reflect.Copy(rv, reflect.ValueOf(d.Field(name).Bytes(rv.Len())))
+			break
+		}
+		elemTag := tag.Tag{Kind: t.Kind, Zero: t.Zero}
+		d.path.push(name)
+		for i := 0; i < rv.Len() && d.lastError == lastError; i++ {
+			d.structValue(rv.Index(i), elemTag, indexLabel(i), parent)
+		}
+		d.path.pop()
+	default:
+		kind, ok := structKind(t, rv)
+		if !ok {
+			d.fail(call{op: "Struct", label: d.path.join(name)}, d.Pos(), 0, fmt.Errorf("unsupported type %s", rv.Type()))
+			return false
+		}
+		d.Field(name)
+		switch kind {
+		case "u8":
+			setUint(rv, uint64(d.Uint8()))
+		case "u16":
+			setUint(rv, uint64(d.Uint16()))
+		case "u32":
+			setUint(rv, uint64(d.Uint32()))
+		case "u64":
+			setUint(rv, d.Uint64())
+		case "i8":
+			setInt(rv, int64(d.Int8()))
+		case "i16":
+			setInt(rv, int64(d.Int16()))
+		case "i32":
+			setInt(rv, int64(d.Int32()))
+		case "i64":
+			setInt(rv, d.Int64())
+		case "f32":
+			setFloat(rv, float64(d.Float32()))
+		case "f64":
+			setFloat(rv, d.Float64())
+		case "bool":
+			rv.SetBool(d.Bool())
+		case "uvarint":
+			setUint(rv, d.Uvarint())
+		case "varint":
+			setInt(rv, d.Varint())
+		case "uleb128":
+			setUint(rv, d.ULEB128())
+		case "sleb128":
+			setInt(rv, d.SLEB128())
+		}
+	}
+	return true
+}
+
+// structCount returns the string, bytes or slice length given by a len or prefix option.
+func (d *Decoder) structCount(t tag.Tag, name string, parent reflect.Value) (int, bool) {
+	c := call{op: "Struct", label: d.path.join(name)}
+	switch t.Prefix {
+	case "u8":
+		return int(d.Field(name).Uint8()), true
+	case "u16":
+		return int(d.Field(name).Uint16()), true
+	case "u32":
+		return int(d.Field(name).Uint32()), true
+	case "uvarint":
+		return int(d.Field(name).Uvarint()), true
+	}
+	n, err := tagCount(t, parent)
+	if err != nil {
+		d.fail(c, d.Pos(), 0, err)
+		return 0, false
+	}
+	return n, true
+}
+
+// Struct encodes the exported fields of the struct v or v points to, in order, as described by their encdec struct tags.
+// Each field is labeled with its name, so errors and traces read like "Header.Flags" or "Items[2].Name".
+// See the README for the tag format. Invalid tags and unsupported field types are recorded like any other encode error.
+func (e *Encoder) Struct(v interface{}) {
+	label := e.path.take()
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		e.fail(call{op: "Struct", label: label}, e.Pos(), 0, fmt.Errorf("want struct or pointer to struct, got %T", v))
+		return
+	}
+	if label != "" {
+		e.path.push(label)
+		defer e.path.pop()
+	}
+	e.structFields(rv)
+}
+
+// structFields encodes each exported field of rv.
+// Fields whose type implements Marshaler are encoded with Value instead.
+func (e *Encoder) structFields(rv reflect.Value) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		t, err := tag.Parse(sf.Tag.Get("encdec"))
+		if err != nil {
+			e.fail(call{op: "Struct", label: e.path.join(sf.Name)}, e.Pos(), 0, err)
+			return
+		}
+		if t.Skip {
+			continue
+		}
+		if !e.structValue(rv.Field(i), t, sf.Name, rv) {
+			return
+		}
+	}
+}
+
+// structValue encodes a single value named name from rv, checking len=Field counts against parent.
+// It returns false if encoding should stop.
+func (e *Encoder) structValue(rv reflect.Value, t tag.Tag, name string, parent reflect.Value) bool {
+	if t.Order != "" {
+		order := e.order
+		e.order = tagOrder(t.Order)
+		defer func() { e.order = order }()
+	}
+	if m, ok := structMarshaler(t, rv); ok {
+		e.Field(name).Value(m)
+		return true
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		e.path.push(name)
+		e.structFields(rv)
+		e.path.pop()
+	case reflect.String:
+		err := t.CheckString()
+		if err != nil {
+			e.fail(call{op: "Struct", label: e.path.join(name)}, e.Pos(), 0, err)
+			return false
+		}
+		switch {
+		case t.Zero:
+			e.Field(name).StringZero(rv.String())
+		case t.Prefix == "u8":
+			e.Field(name).StringLenPrefixUint8(rv.String())
+		case t.Prefix == "u16":
+			e.Field(name).StringLenPrefixUint16(rv.String())
+		case t.Prefix == "u32":
+			e.Field(name).StringLenPrefixUint32(rv.String())
+		default:
+			n, ok := e.structCount(t, name, parent, rv.Len(), false)
+			if !ok {
+				return false
+			}
+			e.Field(name).StringFixed(rv.String(), n)
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice {
+			if _, ok := e.structCount(t, name, parent, rv.Len(), true); !ok {
+				return false
+			}
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 && (t.Kind == "" || t.Kind == "bytes" || t.Kind == "u8") {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			e.Field(name).Bytes(b)
+			break
+		}
+		elemTag := tag.Tag{Kind: t.Kind, Zero: t.Zero}
+		e.path.push(name)
+		for i := 0; i < rv.Len(); i++ {
+			e.structValue(rv.Index(i), elemTag, indexLabel(i), parent)
+		}
+		e.path.pop()
+	default:
+		kind, ok := structKind(t, rv)
+		if !ok {
+			e.fail(call{op: "Struct", label: e.path.join(name)}, e.Pos(), 0, fmt.Errorf("unsupported type %s", rv.Type()))
+			return false
+		}
+		u, i, f := getNumber(rv)
+		e.Field(name)
+		switch kind {
+		case "u8":
+			e.Uint8(uint8(u))
+		case "u16":
+			e.Uint16(uint16(u))
+		case "u32":
+			e.Uint32(uint32(u))
+		case "u64":
+			e.Uint64(u)
+		case "i8":
+			e.Int8(int8(i))
+		case "i16":
+			e.Int16(int16(i))
+		case "i32":
+			e.Int32(int32(i))
+		case "i64":
+			e.Int64(i)
+		case "f32":
+			e.Float32(float32(f))
+		case "f64":
+			e.Float64(f)
+		case "bool":
+			e.Bool(rv.Bool())
+		case "uvarint":
+			e.Uvarint(u)
+		case "varint":
+			e.Varint(i)
+		case "uleb128":
+			e.ULEB128(u)
+		case "sleb128":
+			e.SLEB128(i)
+		}
+	}
+	return true
+}
+
+// structCount writes the inline count prefix of a string, bytes or slice of length n,
+// or returns the count of its len option, which must equal n if exact.
+func (e *Encoder) structCount(t tag.Tag, name string, parent reflect.Value, n int, exact bool) (int, bool) {
+	c := call{op: "Struct", label: e.path.join(name)}
+	switch t.Prefix {
+	case "u8":
+		if !e.Field(name).checkCount("Struct", n, 0xff) {
+			return 0, false
+		}
+		e.Field(name).Uint8(uint8(n))
+		return n, true
+	case "u16":
+		if !e.Field(name).checkCount("Struct", n, 0xffff) {
+			return 0, false
+		}
+		e.Field(name).Uint16(uint16(n))
+		return n, true
+	case "u32":
+		if !e.Field(name).checkCount("Struct", n, 0xffffffff) {
+			return 0, false
+		}
+		e.Field(name).Uint32(uint32(n))
+		return n, true
+	case "uvarint":
+		e.Field(name).Uvarint(uint64(n))
+		return n, true
+	}
+	count, err := tagCount(t, parent)
+	if err != nil {
+		e.fail(c, e.Pos(), 0, err)
+		return 0, false
+	}
+	if exact && count != n {
+		e.fail(c, e.Pos(), 0, fmt.Errorf("length %d does not match len=%s of %d", n, t.Len, count))
+		return 0, false
+	}
+	return count, true
+}
+
+// structMarshaler returns rv as a Marshaler if it or its address implements one and t does not force a kind.
+func structMarshaler(t tag.Tag, rv reflect.Value) (Marshaler, bool) {
+	if t.Kind != "" {
+		return nil, false
+	}
+	if m, ok := rv.Interface().(Marshaler); ok {
+		return m, true
+	}
+	if rv.CanAddr() {
+		m, ok := rv.Addr().Interface().(Marshaler)
+		return m, ok
+	}
+	if reflect.PointerTo(rv.Type()).Implements(reflect.TypeOf((*Marshaler)(nil)).Elem()) {
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		return ptr.Interface().(Marshaler), true
+	}
+	return nil, false
+}
+
+// tagCount returns the count given by a len=N or len=Field option, looking up fields in parent.
+func tagCount(t tag.Tag, parent reflect.Value) (int, error) {
+	if t.Len == "" {
+		return 0, fmt.Errorf("missing len, prefix or zero option")
+	}
+	if n, ok := t.Count(); ok {
+		return n, nil
+	}
+	name, _ := t.LenField()
+	f := parent.FieldByName(name)
+	if !f.IsValid() {
+		return 0, fmt.Errorf("len field %s not found", name)
+	}
+	u, i, _ := getNumber(f)
+	switch f.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int(u), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i < 0 {
+			return 0, fmt.Errorf("len field %s is negative", name)
+		}
+		return int(i), nil
+	}
+	return 0, fmt.Errorf("len field %s is not an integer", name)
+}
+
+// structKind returns the value kind of a numeric or bool field.
+func structKind(t tag.Tag, rv reflect.Value) (string, bool) {
+	kind := t.Kind
+	if kind == "" {
+		var ok bool
+		kind, ok = tag.Infer(rv.Kind().String())
+		if !ok {
+			return "", false
+		}
+	}
+	if _, ok := tag.Method(kind); !ok {
+		return "", false
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return kind, kind == "bool"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		return kind, kind != "bool"
+	}
+	return "", false
+}
+
+// setUint stores u in a numeric rv.
+func setUint(rv reflect.Value, u uint64) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		rv.SetInt(int64(u))
+	case reflect.Float32, reflect.Float64:
+		rv.SetFloat(float64(u))
+	default:
+		rv.SetUint(u)
+	}
+}
+
+// setInt stores i in a numeric rv.
+func setInt(rv reflect.Value, i int64) {
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		rv.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		rv.SetFloat(float64(i))
+	default:
+		rv.SetInt(i)
+	}
+}
+
+// setFloat stores f in a numeric rv.
+func setFloat(rv reflect.Value, f float64) {
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		rv.SetUint(uint64(f))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		rv.SetInt(int64(f))
+	default:
+		rv.SetFloat(f)
+	}
+}
+
+// getNumber returns the value of a numeric rv as each of uint64, int64 and float64.
+func getNumber(rv reflect.Value) (uint64, int64, float64) {
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		return u, int64(u), float64(u)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := rv.Int()
+		return uint64(i), i, float64(i)
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		return uint64(f), int64(f), f
+	}
+	return 0, 0, 0
+}
+
+// tagOrder returns the byte order of an le or be option.
+func tagOrder(order string) binary.ByteOrder {
+	if order == "be" {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
@@ -0,0 +1,160 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type taggedStruct struct {
+	Val1           int16
+	Val2           uint32
+	SomeStr1       string `encdec:"len=3"`
+	SomeStr2       string `encdec:"prefix=u32"`
+	SomeStrZero    string `encdec:"zero"`
+	Val3           float32
+	SomeBytes      []byte `encdec:"len=4"`
+	Count          uint32
+	SomeSubStructs []taggedSubStruct `encdec:"len=Count"`
+	Flags          uint16            `encdec:"be"`
+	Small          int               `encdec:"u8"`
+	Ignored        string            `encdec:"-"`
+	unexported     string
+}
+
+type taggedSubStruct struct {
+	Val1 bool
+	Val2 float64
+	Val3 [3]float32
+}
+
+func TestStruct(t *testing.T) {
+	data := []byte{
+		0x01, 0x00, // Val1
+		0x02, 0x00, 0x00, 0x00, // Val2
+		0x61, 0x62, 0x63, // SomeStr1
+		0x04, 0x00, 0x00, 0x00, 0x64, 0x65, 0x66, 0x67, // SomeStr2
+		0x68, 0x69, 0x6a, 0x6b, 0x00, // SomeStrZero
+		0x00, 0x00, 0x80, 0x3f, // Val3
+		0x01, 0x02, 0x03, 0x04, // SomeBytes
+		0x02, 0x00, 0x00, 0x00, // Count
+		0x01,                                           // SomeSubStructs[0].Val1
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f, // SomeSubStructs[0].Val2
+		0x00, 0x00, 0x80, 0x3f, // SomeSubStructs[0].Val3[0]
+		0x00, 0x00, 0x00, 0x40, // SomeSubStructs[0].Val3[1]
+		0x00, 0x00, 0x40, 0x40, // SomeSubStructs[0].Val3[2]
+		0x00,                                           // SomeSubStructs[1].Val1
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f, // SomeSubStructs[1].Val2
+		0x00, 0x00, 0x80, 0x3f, // SomeSubStructs[1].Val3[0]
+		0x00, 0x00, 0x80, 0x3f, // SomeSubStructs[1].Val3[1]
+		0x00, 0x00, 0x80, 0x3f, // SomeSubStructs[1].Val3[2]
+		0x12, 0x34, // Flags
+		0xff, // Small
+	}
+	want := taggedStruct{
+		Val1:        1,
+		Val2:        2,
+		SomeStr1:    "abc",
+		SomeStr2:    "defg",
+		SomeStrZero: "hijk",
+		Val3:        1,
+		SomeBytes:   []byte{1, 2, 3, 4},
+		Count:       2,
+		SomeSubStructs: []taggedSubStruct{
+			{Val1: true, Val2: 1, Val3: [3]float32{1, 2, 3}},
+			{Val1: false, Val2: 1, Val3: [3]float32{1, 1, 1}},
+		},
+		Flags: 0x1234,
+		Small: 255,
+	}
+
+	dec := NewDecoder(bytes.NewReader(data), binary.LittleEndian)
+	got := taggedStruct{}
+	dec.Struct(&got)
+	if dec.Error() != nil {
+		t.Fatalf("decode: %v", dec.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Struct() = %+v, want %+v", got, want)
+	}
+
+	w := bytes.NewBuffer(nil)
+	enc := NewEncoder(w, binary.LittleEndian)
+	enc.Struct(&want)
+	if enc.Error() != nil {
+		t.Fatalf("encode: %v", enc.Error())
+	}
+	if !bytes.Equal(w.Bytes(), data) {
+		t.Fatalf("Struct() wrote % x, want % x", w.Bytes(), data)
+	}
+
+	dec = NewDecoder(bytes.NewReader(data[:60]), binary.LittleEndian)
+	dec.Field("def").Struct(&got)
+	var decErr *DecodeError
+	if !errors.As(dec.Error(), &decErr) || decErr.Field != "def.SomeSubStructs[1].Val2" {
+		t.Fatalf("Error() = %v, want failure in def.SomeSubStructs[1].Val2", dec.Error())
+	}
+
+	want.Count = 3
+	enc = NewEncoder(bytes.NewBuffer(nil), binary.LittleEndian)
+	enc.Struct(want)
+	var encErr *EncodeError
+	if !errors.As(enc.Error(), &encErr) || encErr.Field != "SomeSubStructs" {
+		t.Fatalf("Error() = %v, want len mismatch on SomeSubStructs", enc.Error())
+	}
+
+	w.Reset()
+	enc = NewEncoder(w, binary.LittleEndian)
+	enc.Struct(struct {
+		Data []byte `encdec:"prefix=u8"`
+	}{Data: make([]byte, 300)})
+	if !errors.As(enc.Error(), &encErr) || encErr.Field != "Data" || w.Len() != 0 {
+		t.Fatalf("Error() = %v after writing %d bytes, want count overflow on Data", enc.Error(), w.Len())
+	}
+
+	enc = NewEncoder(w, binary.LittleEndian)
+	enc.Struct(struct {
+		Name string `encdec:"prefix=u8"`
+	}{Name: string(make([]byte, 256))})
+	if !errors.As(enc.Error(), &encErr) || encErr.Field != "Name" || w.Len() != 0 {
+		t.Fatalf("Error() = %v after writing %d bytes, want count overflow on Name", enc.Error(), w.Len())
+	}
+}
+
+func TestStructZeroStrings(t *testing.T) {
+	// zero with a count applies to each string of a slice
+	type names struct {
+		List []string `encdec:"zero,prefix=u8"`
+	}
+	data := []byte{0x02, 'a', 'b', 0x00, 'c', 0x00}
+	enc := NewBytesEncoder(binary.LittleEndian)
+	enc.Struct(names{List: []string{"ab", "c"}})
+	if got := enc.Encoded(); enc.Error() != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Struct() wrote % x, %v, want % x", got, enc.Error(), data)
+	}
+	got := names{}
+	dec := NewBytesDecoder(data, binary.LittleEndian)
+	dec.Struct(&got)
+	if dec.Error() != nil || !reflect.DeepEqual(got.List, []string{"ab", "c"}) {
+		t.Fatalf("Struct() = %q, %v", got.List, dec.Error())
+	}
+
+	// a zero terminated string has no count of its own
+	type name struct {
+		Name string `encdec:"zero,len=4"`
+	}
+	enc = NewBytesEncoder(binary.LittleEndian)
+	enc.Struct(name{Name: "ab"})
+	var encErr *EncodeError
+	if !errors.As(enc.Error(), &encErr) || encErr.Field != "Name" || enc.Len() != 0 {
+		t.Fatalf("Error() = %v after writing %d bytes, want zero with len on Name", enc.Error(), enc.Len())
+	}
+	dec = NewBytesDecoder([]byte{'a', 'b', 0x00, 0x00}, binary.LittleEndian)
+	dec.Struct(&name{})
+	var decErr *DecodeError
+	if !errors.As(dec.Error(), &decErr) || decErr.Field != "Name" || dec.Pos() != 0 {
+		t.Fatalf("Error() = %v at %d, want zero with len on Name", dec.Error(), dec.Pos())
+	}
+}
@@ -0,0 +1,83 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// TraceEntry describes a single Decoder or Encoder call recorded in debug mode.
+type TraceEntry struct {
+	Offset int64            // position the call started at, counted from the start of its Region
+	Len    int              // number of bytes read or written
+	Op     string           // method that was called, e.g. "Uint32"
+	Label  string           // full field label, empty if unlabeled
+	Value  interface{}      // decoded or encoded value
+	Order  binary.ByteOrder // byte order in effect for the call
+	Raw    []byte           // bytes read or written
+	// Region is 0 for calls on the stream itself. Calls inside a region read or written through a transform,
+	// such as one pushed by PushTransform, have the number of the region, counting from 1 in the order they were opened.
+	// The call opening a region is recorded in the enclosing one, with the number of the new region as its Value.
+	Region int
+}
+
+// String returns a single line summary of the entry.
+func (t TraceEntry) String() string {
+	name := t.Op
+	if t.Label != "" {
+		name = t.Label + " (" + t.Op + ")"
+	}
+	return fmt.Sprintf("0x%08x +%d %s = %s [%s]", t.Offset, t.Len, name, formatValue(t.Value), t.Order)
+}
+
+// tracer collects trace entries and the raw bytes of every call.
+type tracer struct {
+	entries []TraceEntry
+	buf     bytes.Buffer
+	pending bytes.Buffer
+	region  int // region of the calls being traced, see TraceEntry.Region
+	regions int // number of regions opened so far
+}
+
+// record stores an entry for c with the bytes collected in pending.
+func (t *tracer) record(c call, value interface{}, order binary.ByteOrder) {
+	raw := make([]byte, t.pending.Len())
+	copy(raw, t.pending.Bytes())
+	t.pending.Reset()
+	t.buf.Write(raw)
+	t.entries = append(t.entries, TraceEntry{
+		Offset: c.pos,
+		Len:    len(raw),
+		Op:     c.op,
+		Label:  c.label,
+		Value:  value,
+		Order:  order,
+		Raw:    raw,
+		Region: c.region,
+	})
+}
+
+// openRegion returns the number of a new region.
+func (t *tracer) openRegion() int {
+	t.regions++
+	return t.regions
+}
+
+// reset discards all recorded entries.
+func (t *tracer) reset() {
+	t.entries = nil
+	t.buf.Reset()
+	t.pending.Reset()
+}
+
+// formatValue renders a traced value for display.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("% x", v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
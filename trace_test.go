@@ -0,0 +1,56 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	data := []byte{
+		0x34, 0x12, // version
+		0x01,                   // enabled
+		0x03, 0x61, 0x62, 0x63, // name
+	}
+	dec := NewDecoder(bytes.NewReader(data), binary.LittleEndian)
+	dec.SetDebugMode(true)
+	dec.Field("version").Uint16()
+	dec.Bool()
+	dec.Field("name").StringLenPrefixUint8()
+
+	entries := dec.Trace()
+	if len(entries) != 3 {
+		t.Fatalf("Trace() = %+v, want 3 entries", entries)
+	}
+	want := []TraceEntry{
+		{Offset: 0, Len: 2, Op: "Uint16", Label: "version", Value: uint16(0x1234), Raw: data[0:2]},
+		{Offset: 2, Len: 1, Op: "Bool", Value: true, Raw: data[2:3]},
+		{Offset: 3, Len: 4, Op: "StringLenPrefixUint8", Label: "name", Value: "abc", Raw: data[3:7]},
+	}
+	for i, entry := range entries {
+		w := want[i]
+		if entry.Offset != w.Offset || entry.Len != w.Len || entry.Op != w.Op || entry.Label != w.Label ||
+			entry.Value != w.Value || !bytes.Equal(entry.Raw, w.Raw) || entry.Order != binary.LittleEndian {
+			t.Fatalf("entry %d = %+v, want %+v", i, entry, w)
+		}
+	}
+	if !bytes.Equal(dec.DebugBuf(), data) {
+		t.Fatalf("DebugBuf() = % x, want % x", dec.DebugBuf(), data)
+	}
+	if entries[0].String() != `0x00000000 +2 version (Uint16) = 4660 [LittleEndian]` {
+		t.Fatalf("unexpected entry string: %s", entries[0])
+	}
+
+	w := bytes.NewBuffer(nil)
+	enc := NewEncoder(w, binary.BigEndian)
+	enc.SetDebugMode(true)
+	enc.Field("version").Uint16(0x1234)
+	enc.StringZero("hi")
+	entries = enc.Trace()
+	if len(entries) != 2 || entries[1].Offset != 2 || !bytes.Equal(entries[1].Raw, []byte("hi\x00")) {
+		t.Fatalf("unexpected encoder trace: %+v", entries)
+	}
+	if !bytes.Equal(enc.DebugBuf(), w.Bytes()) {
+		t.Fatalf("DebugBuf() = % x, want % x", enc.DebugBuf(), w.Bytes())
+	}
+}
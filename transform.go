@@ -0,0 +1,287 @@
+package encdec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNoTransform is the cause recorded when PopTransform is called without a matching PushTransform.
+var ErrNoTransform = errors.New("no transform to pop")
+
+// ReadTransform wraps the raw stream of a Decoder region, such as a decompressor or decrypter.
+type ReadTransform func(r io.Reader) (io.Reader, error)
+
+// WriteTransform wraps the raw stream of an Encoder region, such as a compressor or encrypter.
+// If the writer it returns is an io.Closer, PopTransform closes it to write out anything it holds back.
+type WriteTransform func(w io.Writer) (io.Writer, error)
+
+// readLayer is a transform pushed onto a Decoder.
+type readLayer struct {
+	raw    io.ReadSeeker
+	src    *transformSource
+	r      io.Reader
+	region int // trace region of the stream beneath
+}
+
+// PushTransform reads everything from here on through t, until PopTransform.
+// Inside the region the stream only moves forward and its length is unknown, and Pos and error offsets count transformed bytes from its start.
+// RawPos tells where the transform has read up to in the stream beneath it.
+// In debug mode, calls inside the region are traced with its own TraceEntry.Region.
+func (d *Decoder) PushTransform(t ReadTransform) {
+	c := d.begin("PushTransform")
+	d.bitLeft = 0
+	src := &transformSource{r: d.r, isPeek: canPeek(d.r), start: d.Pos()}
+	r, err := t(src)
+	if err != nil {
+		d.fail(c, src.start, 0, err)
+		r = errStream{err}
+	}
+	region := d.trace.openRegion()
+	traceRead(d, c, region)
+	d.transforms = append(d.transforms, readLayer{raw: d.r, src: src, r: r, region: d.trace.region})
+	d.r = &stream{r: bufio.NewReader(r)}
+	d.trace.region = region
+}
+
+// PopTransform ends the region started by the last PushTransform, closing the transform if it is an io.Closer.
+// Reading continues right after the raw bytes the transform consumed. It returns any failure.
+func (d *Decoder) PopTransform() error {
+	c := d.begin("PopTransform")
+	d.bitLeft = 0
+	n := len(d.transforms)
+	if n == 0 {
+		d.fail(c, d.Pos(), 0, ErrNoTransform)
+		return d.lastError
+	}
+	l := d.transforms[n-1]
+	d.transforms = d.transforms[:n-1]
+	d.r = l.raw
+	d.trace.region = l.region
+	var err error
+	if closer, ok := l.r.(io.Closer); ok {
+		err = closer.Close()
+	}
+	if releaseErr := l.src.release(); err == nil {
+		err = releaseErr
+	}
+	if err != nil {
+		d.fail(c, d.Pos(), 0, err)
+		return d.lastError
+	}
+	return nil
+}
+
+// RawPos returns the position in the stream beneath every transform pushed by PushTransform, or Pos without one.
+// It counts the raw bytes read by the outermost transform, which may run ahead of the data decoded so far.
+func (d *Decoder) RawPos() int64 {
+	if len(d.transforms) == 0 {
+		return d.Pos()
+	}
+	src := d.transforms[0].src
+	return src.start + src.n
+}
+
+// transformSource is the raw stream read by a transform.
+// It buffers reads and implements io.ByteReader, so decompressors take only the bytes they use,
+// and release leaves the stream just after them.
+type transformSource struct {
+	r      io.ReadSeeker
+	isPeek bool // r can only move forward, so buffered bytes are peeked and skipped once used
+	buf    []byte
+	off    int
+	start  int64
+	n      int64
+}
+
+// Read reads buffered bytes, refilling the buffer when empty.
+func (s *transformSource) Read(p []byte) (int, error) {
+	if s.off == len(s.buf) {
+		err := s.fill()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.buf[s.off:])
+	s.off += n
+	s.n += int64(n)
+	return n, nil
+}
+
+// ReadByte reads a buffered byte, refilling the buffer when empty.
+func (s *transformSource) ReadByte() (byte, error) {
+	if s.off == len(s.buf) {
+		err := s.fill()
+		if err != nil {
+			return 0, err
+		}
+	}
+	b := s.buf[s.off]
+	s.off++
+	s.n++
+	return b, nil
+}
+
+// fill replaces the used up buffer with the next bytes of r.
+func (s *transformSource) fill() error {
+	if s.isPeek {
+		_, err := s.r.Seek(int64(len(s.buf)), io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		b, err := s.r.(peeker).peek(blockSize)
+		s.buf, s.off = append(s.buf[:0], b...), 0
+		if len(b) == 0 {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = io.EOF
+			}
+			return err
+		}
+		return nil
+	}
+	if s.buf == nil {
+		s.buf = make([]byte, blockSize)
+	}
+	n, err := s.r.Read(s.buf[:cap(s.buf)])
+	s.buf, s.off = s.buf[:n], 0
+	if n == 0 {
+		if err == nil {
+			err = io.ErrNoProgress
+		}
+		return err
+	}
+	return nil
+}
+
+// release moves r to just after the bytes used by the transform, skipping or giving back the rest of the buffer.
+func (s *transformSource) release() error {
+	offset := int64(s.off - len(s.buf))
+	if s.isPeek {
+		offset = int64(s.off)
+	}
+	if offset == 0 {
+		return nil
+	}
+	_, err := s.r.Seek(offset, io.SeekCurrent)
+	return err
+}
+
+// errStream fails every read and write with err, in place of a transform that could not be created.
+type errStream struct {
+	err error
+}
+
+// Read fails with err.
+func (s errStream) Read(p []byte) (int, error) {
+	return 0, s.err
+}
+
+// Write fails with err.
+func (s errStream) Write(p []byte) (int, error) {
+	return 0, s.err
+}
+
+// writeLayer is a transform pushed onto an Encoder, with the state it replaces.
+type writeLayer struct {
+	w       io.Writer
+	sink    *transformSink
+	start   int64
+	canSeek bool
+	region  int
+}
+
+// PushTransform writes everything from here on through t, until PopTransform.
+// Inside the region Pos and Len count transformed bytes from its start and RawPos counts the bytes t has written.
+// In debug mode, calls inside the region are traced with its own TraceEntry.Region.
+// A Cipher region set before it applies to the bytes t writes.
+// Placeholders reserved inside the region are held until set, and must be set before PopTransform.
+func (e *Encoder) PushTransform(t WriteTransform) {
+	c := e.begin("PushTransform")
+	e.flushBits(c)
+	if e.unresolved > 0 {
+		e.fail(c, e.Pos(), 0, fmt.Errorf("%d placeholders unset before transform", e.unresolved))
+	}
+	sink := &transformSink{w: e.sink(), pos: e.pos, cipher: e.cipher, cipherStart: e.cipherStart}
+	w, err := t(sink)
+	if err != nil {
+		e.fail(c, e.Pos(), 0, err)
+		w = errStream{err}
+	}
+	region := e.trace.openRegion()
+	traceWrite(e, c, region)
+	e.transforms = append(e.transforms, writeLayer{w: w, sink: sink, start: e.start, canSeek: e.canSeek, region: e.trace.region})
+	e.pos, e.start, e.canSeek, e.cipher = 0, 0, false, nil
+	e.trace.region = region
+}
+
+// PopTransform ends the region started by the last PushTransform, closing the transform if it is an io.Closer.
+// Writing continues right after the raw bytes the transform wrote. It returns any failure.
+func (e *Encoder) PopTransform() error {
+	c := e.begin("PopTransform")
+	e.flushBits(c)
+	n := len(e.transforms)
+	if n == 0 {
+		e.fail(c, e.Pos(), 0, ErrNoTransform)
+		return e.lastError
+	}
+	l := e.transforms[n-1]
+	var err error
+	if e.unresolved > 0 {
+		err = fmt.Errorf("%d placeholders unset in transform", e.unresolved)
+	}
+	if closer, ok := l.w.(io.Closer); ok {
+		closeErr := closer.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	e.transforms = e.transforms[:n-1]
+	e.pos, e.start, e.canSeek = l.sink.pos, l.start, l.canSeek
+	e.cipher, e.cipherStart = l.sink.cipher, l.sink.cipherStart
+	e.trace.region = l.region
+	if err != nil {
+		e.fail(c, e.Pos(), 0, err)
+		return e.lastError
+	}
+	return nil
+}
+
+// RawPos returns the position in the stream beneath every transform pushed by PushTransform, or Pos without one.
+// Transforms that hold data back, such as compressors, may not have written all of it until PopTransform.
+func (e *Encoder) RawPos() int64 {
+	if len(e.transforms) == 0 {
+		return e.Pos()
+	}
+	return e.transforms[0].sink.pos
+}
+
+// sink returns where written bytes go: the innermost transform, or the output of the Encoder.
+func (e *Encoder) sink() io.Writer {
+	if n := len(e.transforms); n > 0 {
+		return e.transforms[n-1].w
+	}
+	return e.out()
+}
+
+// transformSink is the raw stream written by a transform, counting its position
+// and encoding it with the Cipher region it was pushed in, if any.
+type transformSink struct {
+	w           io.Writer
+	pos         int64
+	cipher      Cipher
+	cipherStart int64
+	buf         []byte
+}
+
+// Write writes p to the stream beneath the transform.
+func (s *transformSink) Write(p []byte) (int, error) {
+	if s.cipher != nil {
+		s.buf = append(s.buf[:0], p...)
+		s.cipher.Encode(s.buf, s.pos-s.cipherStart)
+		p = s.buf
+	}
+	n, err := s.w.Write(p)
+	s.pos += int64(n)
+	return n, err
+}
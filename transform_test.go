@@ -0,0 +1,157 @@
+package encdec
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func inflate(r io.Reader) (io.Reader, error) {
+	return flate.NewReader(r), nil
+}
+
+func deflate(w io.Writer) (io.Writer, error) {
+	return flate.NewWriter(w, flate.BestCompression)
+}
+
+// writeTransformExample writes a header, a compressed block with a placeholder inside, and a tail.
+func writeTransformExample(enc *Encoder) {
+	enc.Uint16(0xbeef)
+	enc.PushTransform(deflate)
+	size := enc.ReserveUint16()
+	for i := 0; i < 100; i++ {
+		enc.StringZero("repeated")
+	}
+	size.Set(uint64(enc.Pos()))
+	enc.PopTransform()
+	enc.Uint8(0x7f)
+}
+
+func TestTransform(t *testing.T) {
+	enc := NewBytesEncoder(binary.LittleEndian)
+	writeTransformExample(enc)
+	data := enc.Encoded()
+	if enc.Error() != nil || enc.RawPos() != enc.Pos() || len(data) > 100 {
+		t.Fatalf("encoded %d bytes, %v", len(data), enc.Error())
+	}
+	// an unseekable writer gives the same bytes
+	buf := &bytes.Buffer{}
+	writeTransformExample(NewEncoder(buf, binary.LittleEndian))
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("bytes.Buffer got % x, want % x", buf.Bytes(), data)
+	}
+
+	for _, dec := range []*Decoder{NewBytesDecoder(data, binary.LittleEndian), NewReaderDecoder(bytes.NewReader(data), binary.LittleEndian)} {
+		if v := dec.Uint16(); v != 0xbeef {
+			t.Fatalf("header = %#x", v)
+		}
+		dec.PushTransform(inflate)
+		if n := dec.Field("size").Uint16(); n != 902 {
+			t.Fatalf("size = %d, want 902", n)
+		}
+		names := SliceN(dec, 100, (*Decoder).StringZero)
+		if dec.Pos() != 902 || names[99] != "repeated" || dec.RawPos() <= 2 {
+			t.Fatalf("Pos() = %d, RawPos() = %d, last name %q", dec.Pos(), dec.RawPos(), names[99])
+		}
+		dec.Uint8()
+		var decErr *DecodeError
+		if !errors.As(dec.LastError(), &decErr) || decErr.Offset != 902 {
+			t.Fatalf("read past end of transform: %v, want offset 902", dec.LastError())
+		}
+		if err := dec.PopTransform(); err != nil {
+			t.Fatalf("PopTransform: %v", err)
+		}
+		if v := dec.Uint8(); v != 0x7f || dec.Pos() != int64(len(data)) {
+			t.Fatalf("tail = %#x at %d, want 0x7f at %d", v, dec.Pos(), len(data))
+		}
+	}
+}
+
+func TestTransformCipher(t *testing.T) {
+	key := NewXORCipher([]byte{0x5a, 0xa5})
+	enc := NewBytesEncoder(binary.LittleEndian)
+	enc.SetCipher(key)
+	enc.PushTransform(deflate)
+	enc.StringZero("compressed, then obfuscated")
+	enc.PopTransform()
+	enc.SetCipher(nil)
+	enc.Uint8(0x7f)
+	data := enc.Encoded()
+
+	dec := NewBytesDecoder(data, binary.LittleEndian)
+	dec.SetCipher(key)
+	dec.PushTransform(inflate)
+	s := dec.StringZero()
+	dec.PopTransform()
+	dec.SetCipher(nil)
+	if v := dec.Uint8(); s != "compressed, then obfuscated" || v != 0x7f || dec.Error() != nil {
+		t.Fatalf("got %q, %#x, %v", s, v, dec.Error())
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	dec := NewBytesDecoder([]byte{1, 2, 3, 4}, binary.LittleEndian)
+	if err := dec.PopTransform(); !errors.Is(err, ErrNoTransform) {
+		t.Fatalf("PopTransform without push = %v", err)
+	}
+	errBad := errors.New("bad header")
+	sub := dec.Sub(4)
+	sub.PushTransform(func(r io.Reader) (io.Reader, error) { return nil, errBad })
+	sub.Uint8()
+	if err := sub.Close(); !errors.Is(err, errBad) || len(sub.transforms) != 0 {
+		t.Fatalf("failed transform: %v", err)
+	}
+
+	enc := NewBytesEncoder(binary.LittleEndian)
+	enc.PushTransform(deflate)
+	enc.ReserveUint8()
+	if err := enc.PopTransform(); err == nil {
+		t.Fatalf("PopTransform with an unset placeholder succeeded")
+	}
+}
+
+func TestTransformTrace(t *testing.T) {
+	enc := NewBytesEncoder(binary.LittleEndian)
+	enc.SetDebugMode(true)
+	enc.Field("magic").Uint32(0x21434241)
+	enc.PushTransform(deflate)
+	size := enc.Field("size").ReserveUint8()
+	enc.Field("inner").Uint16(7)
+	size.Set(3)
+	enc.PopTransform()
+	enc.Field("tail").Uint8(0x7f)
+	if enc.Error() != nil {
+		t.Fatalf("encode: %v", enc.Error())
+	}
+	for _, entry := range enc.Trace() {
+		region := 0
+		if entry.Label == "size" || entry.Label == "inner" {
+			region = 1
+		}
+		if entry.Region != region {
+			t.Fatalf("%s traced in region %d, want %d", entry, entry.Region, region)
+		}
+	}
+
+	dec := NewBytesDecoder(enc.Encoded(), binary.LittleEndian)
+	dec.SetDebugMode(true)
+	dec.Field("magic").Uint32()
+	dec.PushTransform(inflate)
+	dec.Field("size").Uint8()
+	dec.Field("inner").Uint16()
+	dec.PopTransform()
+	dec.Field("tail").Uint8()
+	entries := dec.Trace()
+	if push := entries[1]; push.Op != "PushTransform" || push.Offset != 4 || push.Region != 0 || push.Value != 1 {
+		t.Fatalf("PushTransform entry %+v", push)
+	}
+	// the inflated fields are drawn apart from the raw bytes, not over magic
+	got := dec.Hexdump(false)
+	if !strings.HasPrefix(got, "00000000 |41 42 43 21 ") || !strings.HasSuffix(got, "\nregion 1\n00000000 |03|07 00                                         |...             |  size = 3, inner = 7\n") {
+		t.Fatalf("Hexdump() =\n%s", got)
+	}
+}
@@ -0,0 +1,122 @@
+package encdec
+
+// Unmarshaler is implemented by types that decode themselves, such as a vector or header record
+// reused in several formats. Failures are recorded on the Decoder like any other read.
+type Unmarshaler interface {
+	DecodeFrom(dec *Decoder)
+}
+
+// Marshaler is implemented by types that encode themselves, the counterpart of Unmarshaler.
+type Marshaler interface {
+	EncodeTo(enc *Encoder)
+}
+
+// unmarshalerPtr constrains P to a pointer to T that implements Unmarshaler.
+type unmarshalerPtr[T any] interface {
+	*T
+	Unmarshaler
+}
+
+// marshalerPtr constrains P to a pointer to T that implements Marshaler, covering both value and pointer receivers.
+type marshalerPtr[T any] interface {
+	*T
+	Marshaler
+}
+
+// Value decodes u by calling its DecodeFrom method.
+// A pending field label becomes a scope for every read made by u, e.g. dec.Field("pos").Value(&v) labels "pos.x".
+func (d *Decoder) Value(u Unmarshaler) {
+	name := d.path.next
+	d.path.next = ""
+	if name != "" {
+		d.path.push(name)
+		defer d.path.pop()
+	}
+	u.DecodeFrom(d)
+}
+
+// Value encodes m by calling its EncodeTo method.
+// A pending field label becomes a scope for every write made by m, e.g. enc.Field("pos").Value(v) labels "pos.x".
+func (e *Encoder) Value(m Marshaler) {
+	name := e.path.next
+	e.path.next = ""
+	if name != "" {
+		e.path.push(name)
+		defer e.path.pop()
+	}
+	m.EncodeTo(e)
+}
+
+// Values decodes n elements of an Unmarshaler type, e.g. encdec.Values[Vector3](dec, 8).
+// Elements are labeled by index under any pending field label, and decoding stops at the first error.
+func Values[T any, P unmarshalerPtr[T]](d *Decoder, n int) []T {
+	name := d.path.next
+	d.path.next = ""
+	return decodeSlice(d, "Values", name, n, decodeValue[T, P])
+}
+
+// ValuesU8 decodes a uint8 count followed by that many elements of an Unmarshaler type.
+func ValuesU8[T any, P unmarshalerPtr[T]](d *Decoder) []T {
+	name := d.path.next
+	n := int(d.Uint8())
+	return decodeSlice(d, "ValuesU8", name, n, decodeValue[T, P])
+}
+
+// ValuesU16 decodes a uint16 count followed by that many elements of an Unmarshaler type.
+func ValuesU16[T any, P unmarshalerPtr[T]](d *Decoder) []T {
+	name := d.path.next
+	n := int(d.Uint16())
+	return decodeSlice(d, "ValuesU16", name, n, decodeValue[T, P])
+}
+
+// ValuesU32 decodes a uint32 count followed by that many elements of an Unmarshaler type,
+// like the someSubStructs loop in the examples.
+func ValuesU32[T any, P unmarshalerPtr[T]](d *Decoder) []T {
+	name := d.path.next
+	n := d.count("ValuesU32", name, uint64(d.Uint32()))
+	return decodeSlice(d, "ValuesU32", name, n, decodeValue[T, P])
+}
+
+// ValuesUvarint decodes a uvarint count followed by that many elements of an Unmarshaler type.
+func ValuesUvarint[T any, P unmarshalerPtr[T]](d *Decoder) []T {
+	name := d.path.next
+	n := d.count("ValuesUvarint", name, d.Uvarint())
+	return decodeSlice(d, "ValuesUvarint", name, n, decodeValue[T, P])
+}
+
+// decodeValue decodes a single element of an Unmarshaler type.
+func decodeValue[T any, P unmarshalerPtr[T]](d *Decoder) T {
+	var v T
+	P(&v).DecodeFrom(d)
+	return v
+}
+
+// PutValues encodes each element of s, the counterpart of Values.
+func PutValues[T any, P marshalerPtr[T]](e *Encoder, s []T) {
+	PutSlice(e, s, encodeValue[T, P])
+}
+
+// PutValuesU8 encodes the length of s as a uint8 followed by each element, the counterpart of ValuesU8.
+func PutValuesU8[T any, P marshalerPtr[T]](e *Encoder, s []T) {
+	PutSliceU8(e, s, encodeValue[T, P])
+}
+
+// PutValuesU16 encodes the length of s as a uint16 followed by each element, the counterpart of ValuesU16.
+func PutValuesU16[T any, P marshalerPtr[T]](e *Encoder, s []T) {
+	PutSliceU16(e, s, encodeValue[T, P])
+}
+
+// PutValuesU32 encodes the length of s as a uint32 followed by each element, the counterpart of ValuesU32.
+func PutValuesU32[T any, P marshalerPtr[T]](e *Encoder, s []T) {
+	PutSliceU32(e, s, encodeValue[T, P])
+}
+
+// PutValuesUvarint encodes the length of s as a uvarint followed by each element, the counterpart of ValuesUvarint.
+func PutValuesUvarint[T any, P marshalerPtr[T]](e *Encoder, s []T) {
+	PutSliceUvarint(e, s, encodeValue[T, P])
+}
+
+// encodeValue encodes a single element of a Marshaler type.
+func encodeValue[T any, P marshalerPtr[T]](e *Encoder, v T) {
+	P(&v).EncodeTo(e)
+}
@@ -0,0 +1,111 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type valueVector3 struct {
+	x, y, z float32
+}
+
+func (v *valueVector3) DecodeFrom(dec *Decoder) {
+	v.x = dec.Field("x").Float32()
+	v.y = dec.Field("y").Float32()
+	v.z = dec.Field("z").Float32()
+}
+
+func (v valueVector3) EncodeTo(enc *Encoder) {
+	enc.Field("x").Float32(v.x)
+	enc.Field("y").Float32(v.y)
+	enc.Field("z").Float32(v.z)
+}
+
+func TestValue(t *testing.T) {
+	data := []byte{
+		0x00, 0x00, 0x80, 0x3f, 0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x40, 0x40, // pos
+		0x02, 0x00, 0x00, 0x00, // points count
+		0x00, 0x00, 0x80, 0x3f, 0x00, 0x00, 0x80, 0x3f, 0x00, 0x00, 0x80, 0x3f, // points[0]
+		0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00, 0x40, // points[1]
+	}
+	dec := NewDecoder(bytes.NewReader(data), binary.LittleEndian)
+	dec.SetDebugMode(true)
+	pos := valueVector3{}
+	dec.Field("pos").Value(&pos)
+	points := ValuesU32[valueVector3](dec.Field("points"))
+	if dec.Error() != nil {
+		t.Fatalf("decode: %v", dec.Error())
+	}
+	if pos != (valueVector3{1, 2, 3}) || !reflect.DeepEqual(points, []valueVector3{{1, 1, 1}, {2, 2, 2}}) {
+		t.Fatalf("got pos %v, points %v", pos, points)
+	}
+	entries := dec.Trace()
+	if entries[1].Label != "pos.y" || entries[3].Label != "points" || entries[9].Label != "points[1].z" {
+		t.Fatalf("unexpected labels: %+v", entries)
+	}
+
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf, binary.LittleEndian)
+	enc.Field("pos").Value(pos)
+	PutValuesU32(enc.Field("points"), points)
+	if enc.Error() != nil {
+		t.Fatalf("encode: %v", enc.Error())
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("encoded % x, want % x", buf.Bytes(), data)
+	}
+
+	// a truncated element stops decoding and reports its index
+	dec = NewDecoder(bytes.NewReader(data[12:34]), binary.LittleEndian)
+	points = ValuesU32[valueVector3](dec.Field("points"))
+	var decErr *DecodeError
+	if !errors.Is(dec.Error(), io.ErrUnexpectedEOF) || !errors.As(dec.Error(), &decErr) || decErr.Field != "points[1].y" || len(points) != 2 {
+		t.Fatalf("Error() = %v with %d points, want points[1].y unexpected EOF", dec.Error(), len(points))
+	}
+}
+
+func TestValuesCount(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf, binary.LittleEndian)
+	PutValuesU8(enc.Field("points"), make([]valueVector3, 256))
+	var encErr *EncodeError
+	if !errors.As(enc.Error(), &encErr) || encErr.Field != "points" || buf.Len() != 0 {
+		t.Fatalf("Error() = %v with %d bytes written, want points count overflow", enc.Error(), buf.Len())
+	}
+
+	dec := NewDecoder(bytes.NewReader(nil), binary.LittleEndian)
+	points := Values[valueVector3](dec, -1)
+	if dec.Error() == nil || points != nil {
+		t.Fatalf("Values(-1) = %v, %v, want error", points, dec.Error())
+	}
+}
+
+type valueRecord struct {
+	ID  uint16
+	Pos valueVector3
+}
+
+func TestValueStruct(t *testing.T) {
+	data := []byte{0x07, 0x00, 0x00, 0x00, 0x80, 0x3f, 0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x40, 0x40}
+	dec := NewDecoder(bytes.NewReader(data), binary.LittleEndian)
+	dec.SetDebugMode(true)
+	rec := valueRecord{}
+	dec.Struct(&rec)
+	if dec.Error() != nil || rec != (valueRecord{7, valueVector3{1, 2, 3}}) {
+		t.Fatalf("Struct() = %+v, %v", rec, dec.Error())
+	}
+	if entries := dec.Trace(); len(entries) != 4 || entries[3].Label != "Pos.z" {
+		t.Fatalf("unexpected trace: %+v", entries)
+	}
+
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf, binary.LittleEndian)
+	enc.Struct(rec)
+	if enc.Error() != nil || !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("Struct() wrote % x, %v", buf.Bytes(), enc.Error())
+	}
+}
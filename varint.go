@@ -0,0 +1,167 @@
+package encdec
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Uvarint returns an unsigned varint in the encoding/binary Uvarint wire form (at most 10 bytes).
+func (d *Decoder) Uvarint() uint64 {
+	c := d.begin("Uvarint")
+	v := d.uleb128(c, binary.MaxVarintLen64)
+	traceRead(d, c, v)
+	return v
+}
+
+// Varint returns a zigzag-encoded signed varint, the encoding/binary Varint and protobuf sint64 wire form.
+func (d *Decoder) Varint() int64 {
+	c := d.begin("Varint")
+	ux := d.uleb128(c, binary.MaxVarintLen64)
+	v := int64(ux >> 1)
+	if ux&1 != 0 {
+		v = ^v
+	}
+	traceRead(d, c, v)
+	return v
+}
+
+// ULEB128 returns an unsigned LEB128 value, as used by DWARF and WebAssembly.
+// Redundant padding bytes are accepted as long as the value fits in 64 bits.
+func (d *Decoder) ULEB128() uint64 {
+	c := d.begin("ULEB128")
+	v := d.uleb128(c, 0)
+	traceRead(d, c, v)
+	return v
+}
+
+// SLEB128 returns a signed LEB128 value, as used by DWARF and WebAssembly.
+// Redundant padding bytes are accepted as long as the value fits in 64 bits.
+func (d *Decoder) SLEB128() int64 {
+	c := d.begin("SLEB128")
+	v := d.sleb128(c)
+	traceRead(d, c, v)
+	return v
+}
+
+// uleb128 reads an unsigned LEB128 value of at most max bytes, or any length if max is 0.
+func (d *Decoder) uleb128(c call, max int) uint64 {
+	var v uint64
+	var shift uint
+	buf := d.scratch[:1]
+	d.bitLeft = 0
+	pos := d.Pos()
+	r := d.reader()
+	for i := 0; ; i++ {
+		if max > 0 && i == max {
+			d.fail(c, pos, 0, ErrOverflow)
+			return 0
+		}
+		_, err := io.ReadFull(r, buf)
+		if err != nil {
+			d.fail(c, pos, 0, err)
+			return 0
+		}
+		b := buf[0]
+		payload := uint64(b & 0x7f)
+		if (shift == 63 && payload > 1) || (shift > 63 && payload != 0) {
+			d.fail(c, pos, 0, ErrOverflow)
+			return 0
+		}
+		if shift < 64 {
+			v |= payload << shift
+		}
+		shift += 7
+		if b&0x80 == 0 {
+			return v
+		}
+	}
+}
+
+// sleb128 reads a signed LEB128 value of any length.
+func (d *Decoder) sleb128(c call) int64 {
+	var v int64
+	var shift uint
+	buf := d.scratch[:1]
+	d.bitLeft = 0
+	pos := d.Pos()
+	r := d.reader()
+	for {
+		_, err := io.ReadFull(r, buf)
+		if err != nil {
+			d.fail(c, pos, 0, err)
+			return 0
+		}
+		b := buf[0]
+		payload := b & 0x7f
+		switch {
+		case shift < 63:
+			v |= int64(payload) << shift
+		case shift == 63:
+			if payload != 0 && payload != 0x7f {
+				d.fail(c, pos, 0, ErrOverflow)
+				return 0
+			}
+			v |= int64(payload) << shift
+		default:
+			if (v < 0 && payload != 0x7f) || (v >= 0 && payload != 0) {
+				d.fail(c, pos, 0, ErrOverflow)
+				return 0
+			}
+		}
+		shift += 7
+		if b&0x80 == 0 {
+			if shift < 64 && b&0x40 != 0 {
+				v |= -1 << shift
+			}
+			return v
+		}
+	}
+}
+
+// Uvarint writes v as an unsigned varint in the encoding/binary Uvarint wire form.
+func (e *Encoder) Uvarint(v uint64) {
+	c := e.begin("Uvarint")
+	buf := e.fixed(c, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, v)
+	e.write(c, buf[:n])
+	traceWrite(e, c, v)
+}
+
+// Varint writes v as a zigzag-encoded signed varint, the encoding/binary Varint and protobuf sint64 wire form.
+func (e *Encoder) Varint(v int64) {
+	c := e.begin("Varint")
+	buf := e.fixed(c, binary.MaxVarintLen64)
+	n := binary.PutVarint(buf, v)
+	e.write(c, buf[:n])
+	traceWrite(e, c, v)
+}
+
+// ULEB128 writes v as an unsigned LEB128 value, as used by DWARF and WebAssembly.
+func (e *Encoder) ULEB128(v uint64) {
+	c := e.begin("ULEB128")
+	buf := e.fixed(c, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, v)
+	e.write(c, buf[:n])
+	traceWrite(e, c, v)
+}
+
+// SLEB128 writes v as a signed LEB128 value, as used by DWARF and WebAssembly.
+func (e *Encoder) SLEB128(v int64) {
+	c := e.begin("SLEB128")
+	buf := e.fixed(c, binary.MaxVarintLen64)
+	n := 0
+	x := v
+	for {
+		b := byte(x & 0x7f)
+		x >>= 7
+		if (x == 0 && b&0x40 == 0) || (x == -1 && b&0x40 != 0) {
+			buf[n] = b
+			n++
+			break
+		}
+		buf[n] = b | 0x80
+		n++
+	}
+	e.write(c, buf[:n])
+	traceWrite(e, c, v)
+}
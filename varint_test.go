@@ -0,0 +1,74 @@
+package encdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestVarint(t *testing.T) {
+	unsigned := []uint64{0, 1, 127, 128, 300, 624485, math.MaxUint32, math.MaxUint64}
+	signed := []int64{0, 1, -1, 63, -64, 64, -65, -123456, math.MaxInt64, math.MinInt64}
+
+	w := bytes.NewBuffer(nil)
+	enc := NewEncoder(w, binary.LittleEndian)
+	for _, v := range unsigned {
+		enc.Uvarint(v)
+		enc.ULEB128(v)
+	}
+	for _, v := range signed {
+		enc.Varint(v)
+		enc.SLEB128(v)
+	}
+	if enc.Error() != nil {
+		t.Fatalf("encode: %v", enc.Error())
+	}
+
+	dec := NewDecoder(bytes.NewReader(w.Bytes()), binary.LittleEndian)
+	for _, v := range unsigned {
+		if got := dec.Uvarint(); got != v {
+			t.Fatalf("Uvarint() = %d, want %d", got, v)
+		}
+		if got := dec.ULEB128(); got != v {
+			t.Fatalf("ULEB128() = %d, want %d", got, v)
+		}
+	}
+	for _, v := range signed {
+		if got := dec.Varint(); got != v {
+			t.Fatalf("Varint() = %d, want %d", got, v)
+		}
+		if got := dec.SLEB128(); got != v {
+			t.Fatalf("SLEB128() = %d, want %d", got, v)
+		}
+	}
+	if dec.Error() != nil {
+		t.Fatalf("decode: %v", dec.Error())
+	}
+
+	// known encodings from the DWARF specification
+	dec = NewDecoder(bytes.NewReader([]byte{0xe5, 0x8e, 0x26, 0xc0, 0xbb, 0x78, 0x80, 0x80, 0x00}), binary.LittleEndian)
+	if v := dec.ULEB128(); v != 624485 {
+		t.Fatalf("ULEB128() = %d, want 624485", v)
+	}
+	if v := dec.SLEB128(); v != -123456 {
+		t.Fatalf("SLEB128() = %d, want -123456", v)
+	}
+	if v := dec.ULEB128(); v != 0 || dec.Error() != nil {
+		t.Fatalf("padded ULEB128() = %d, %v, want 0", v, dec.Error())
+	}
+
+	overflow := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02}
+	dec = NewDecoder(bytes.NewReader(overflow), binary.LittleEndian)
+	dec.Uvarint()
+	var decErr *DecodeError
+	if !errors.Is(dec.Error(), ErrOverflow) || !errors.As(dec.Error(), &decErr) || decErr.Op != "Uvarint" {
+		t.Fatalf("Error() = %v, want Uvarint overflow", dec.Error())
+	}
+	dec = NewDecoder(bytes.NewReader(overflow), binary.LittleEndian)
+	dec.SLEB128()
+	if !errors.Is(dec.LastError(), ErrOverflow) {
+		t.Fatalf("LastError() = %v, want overflow", dec.LastError())
+	}
+}